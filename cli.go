@@ -0,0 +1,274 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swap"
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+const usage = `Usage:
+  %[1]s '<snap plugin arguments>'
+        run as Snap plugin, which is done by snapteld
+  %[1]s collect [options]
+        collect metrics directly and print them, options are:
+`
+
+// rateWindow is time between priming collection and collection printed by collect --once,
+// rates are calculated over this time
+var rateWindow = time.Second
+
+// metric is collected metric as printed by collect command
+type metric struct {
+	Namespace string            `json:"namespace"`
+	Value     interface{}       `json:"value"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// runCommand runs command given in args without snapteld and returns exit status
+func runCommand(name string, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(name, stderr)
+		return 2
+	}
+	switch args[0] {
+	case "collect":
+		return collect(name, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		printUsage(name, stdout)
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+	printUsage(name, stderr)
+	return 2
+}
+
+func printUsage(name string, w io.Writer) {
+	fmt.Fprintf(w, usage, name)
+	flags, _ := collectFlags(name)
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+type collectOptions struct {
+	once     bool
+	interval time.Duration
+	count    int
+	format   string
+	config   string
+	root     string
+}
+
+func collectFlags(name string) (*flag.FlagSet, *collectOptions) {
+	opts := &collectOptions{}
+	flags := flag.NewFlagSet(name+" collect", flag.ContinueOnError)
+	flags.BoolVar(&opts.once, "once", false, "collect metrics once and exit (default unless interval is given)")
+	flags.DurationVar(&opts.interval, "interval", 0, "collect metrics repeatedly with given interval, e.g. 5s")
+	flags.IntVar(&opts.count, "count", 0, "number of collections done with interval, 0 collects until interrupted")
+	flags.StringVar(&opts.format, "format", "table", "output format, table or json")
+	flags.StringVar(&opts.config, "config", "", `plugin configuration as JSON object, e.g. {"proc_path": "/host/proc"}`)
+	flags.StringVar(&opts.root, "root", "", "directory in which data sources are looked up instead of /, e.g. mounted root of a host")
+	return flags, opts
+}
+
+// collect runs collection of all metrics exposed by plugin once or with interval
+// and prints collected metrics; rates are calculated against previous collection,
+// so collection which is not printed is done first
+func collect(name string, args []string, stdout, stderr io.Writer) int {
+	flags, opts := collectFlags(name)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return 2
+	}
+	err := opts.validate()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cfg, err := parseConfig(opts.config)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	fs := swap.OSFileSystem
+	if opts.root != "" {
+		fs = swap.DirFileSystem(opts.root)
+	}
+	collector := swap.NewSwapCollectorFS(fs)
+	mts, err := collector.GetMetricTypes(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for i := range mts {
+		mts[i].Config_ = cfg.ConfigDataNode
+	}
+	count := opts.count
+	if opts.interval == 0 {
+		count = 1
+	}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	next := func() <-chan time.Time {
+		return time.After(rateWindow)
+	}
+	if opts.interval != 0 {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		next = func() <-chan time.Time {
+			return ticker.C
+		}
+	}
+	// errors of priming collection are reported by collections which follow
+	collector.CollectMetrics(mts)
+	status := 0
+	for i := 0; count == 0 || i < count; i++ {
+		select {
+		case <-next():
+		case <-interrupted:
+			return status
+		}
+		metrics, err := collector.CollectMetrics(mts)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		if err := printMetrics(stdout, opts.format, metrics, i > 0); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return status
+}
+
+func (opts *collectOptions) validate() error {
+	if opts.once && opts.interval != 0 {
+		return errors.New("once and interval cannot be used together")
+	}
+	if opts.interval < 0 {
+		return fmt.Errorf("invalid interval %s", opts.interval)
+	}
+	if opts.count < 0 {
+		return fmt.Errorf("invalid count %d", opts.count)
+	}
+	if opts.format != "table" && opts.format != "json" {
+		return fmt.Errorf("unknown format %q, expected table or json", opts.format)
+	}
+	return nil
+}
+
+// parseConfig returns plugin configuration given as JSON object of string items
+func parseConfig(s string) (plugin.ConfigType, error) {
+	cfg := plugin.NewPluginConfigType()
+	if s == "" {
+		return cfg, nil
+	}
+	items := map[string]string{}
+	if err := json.Unmarshal([]byte(s), &items); err != nil {
+		return cfg, fmt.Errorf("invalid config: %s", err)
+	}
+	for k, v := range items {
+		cfg.AddItem(k, ctypes.ConfigValueStr{Value: v})
+	}
+	return cfg, nil
+}
+
+// printMetrics prints metrics sorted by namespace, either as table
+// or as JSON array in single line per collection
+func printMetrics(w io.Writer, format string, mts []plugin.MetricType, next bool) error {
+	metrics := make([]metric, len(mts))
+	for i, mt := range mts {
+		metrics[i] = metric{
+			Namespace: mt.Namespace().String(),
+			Value:     mt.Data(),
+			Tags:      mt.Tags(),
+			Timestamp: mt.Timestamp(),
+		}
+	}
+	sort.Sort(byNamespace(metrics))
+	if format == "json" {
+		// JSON has no representation of NaN and infinities
+		for i := range metrics {
+			if f, ok := metrics[i].Value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				metrics[i].Value = nil
+			}
+		}
+		return json.NewEncoder(w).Encode(metrics)
+	}
+	if next {
+		fmt.Fprintln(w)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tVALUE\tTAGS")
+	for _, m := range metrics {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", m.Namespace, formatValue(m.Value), formatTags(m.Tags))
+	}
+	return tw.Flush()
+}
+
+func formatValue(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type byNamespace []metric
+
+func (m byNamespace) Len() int           { return len(m) }
+func (m byNamespace) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byNamespace) Less(i, j int) bool { return m[i].Namespace < m[j].Namespace }
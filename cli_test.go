@@ -0,0 +1,112 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCollectCommand(t *testing.T) {
+	root, _ := ioutil.TempDir("", "swap-cli")
+	defer os.RemoveAll(root)
+	os.MkdirAll(filepath.Join(root, "proc", "sys", "kernel"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "proc", "sys", "kernel", "osrelease"), []byte("4.19.0\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "proc", "swaps"), []byte("Filename Type Size Used Priority\n/dev/sda2 partition 2048 1024 -2\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "proc", "meminfo"), []byte("SwapCached: 0 kB\nSwapTotal: 2048 kB\nSwapFree: 1024 kB\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "proc", "vmstat"), []byte("pgpgin 1\npgpgout 2\npswpin 3\npswpout 4\n"), 0644)
+	cfg := `{"proc_path": "/proc", "log_level": "panic"}`
+	rateWindow = 10 * time.Millisecond
+	run := func(args ...string) (int, string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		status := runCommand("swap", args, stdout, stderr)
+		return status, stdout.String(), stderr.String()
+	}
+	Convey("usage is printed instead of failing without arguments", t, func() {
+		status, _, stderr := run()
+		So(status, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "swap collect [options]")
+		So(stderr, ShouldContainSubstring, "-interval")
+		status, stdout, _ := run("help")
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldContainSubstring, "Usage:")
+		status, _, stderr = run("report")
+		So(status, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, `unknown command "report"`)
+	})
+	Convey("invalid options are rejected", t, func() {
+		for _, args := range [][]string{
+			{"collect", "--once", "--interval", "1s"},
+			{"collect", "--interval", "-1s"},
+			{"collect", "--format", "xml"},
+			{"collect", "--config", `{"proc_path": 1}`},
+			{"collect", "--unknown"},
+			{"collect", "once"},
+		} {
+			status, stdout, _ := run(args...)
+			So(status, ShouldEqual, 2)
+			So(stdout, ShouldBeEmpty)
+		}
+	})
+	Convey("metrics are collected once and printed as JSON", t, func() {
+		status, stdout, _ := run("collect", "--once", "--format", "json", "--root", root, "--config", cfg)
+		So(status, ShouldEqual, 0)
+		metrics := []metric{}
+		So(json.Unmarshal([]byte(stdout), &metrics), ShouldBeNil)
+		values := map[string]interface{}{}
+		for _, m := range metrics {
+			values[m.Namespace] = m.Value
+		}
+		So(values["/intel/procfs/swap/all/free_bytes"], ShouldEqual, 1024*1024)
+		So(values["/intel/procfs/swap/device/dev_sda2/used_bytes"], ShouldEqual, 1024*1024)
+		So(values["/intel/procfs/swap/features/vmstat_io"], ShouldEqual, 1)
+		// swap IO counters did not change since priming collection
+		So(values["/intel/procfs/swap/io/in_pages_per_sec"], ShouldEqual, 0)
+		So(values["/intel/procfs/swap/io/out_bytes_per_sec"], ShouldEqual, 0)
+	})
+	Convey("metrics are collected with interval and printed as tables", t, func() {
+		status, stdout, _ := run("collect", "--interval", "10ms", "--count", "2", "--root", root, "--config", cfg)
+		So(status, ShouldEqual, 0)
+		So(strings.Count(stdout, "NAMESPACE"), ShouldEqual, 2)
+		So(stdout, ShouldContainSubstring, "/intel/procfs/swap/all/free_bytes")
+		So(stdout, ShouldContainSubstring, "1048576")
+		for _, line := range strings.Split(stdout, "\n") {
+			if strings.HasPrefix(line, "/intel/procfs/swap/io/") {
+				So(strings.Fields(line)[1], ShouldEqual, "0")
+			}
+		}
+	})
+	Convey("collection fails when data sources are not available", t, func() {
+		status, stdout, stderr := run("collect", "--root", root, "--config", `{"proc_path": "/missing"}`)
+		So(status, ShouldEqual, 1)
+		So(stdout, ShouldBeEmpty)
+		So(stderr, ShouldNotBeEmpty)
+	})
+}
@@ -23,6 +23,8 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 
 	// Import the snap plugin library
 	"github.com/intelsdi-x/snap-plugin-collector-swap/swap"
@@ -31,15 +33,16 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 )
 
-// plugin bootstrap
+// plugin bootstrap, snapteld starts plugin with JSON object argument,
+// other arguments are commands run without snapteld
 func main() {
-	swapPlugin := swap.NewSwapCollector()
-	if swapPlugin == nil {
-		panic("Failed to initialize plugin\n")
+	if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "{") {
+		plugin.Start(
+			swap.Meta(),
+			swap.NewSwapCollector(),
+			os.Args[1],
+		)
+		return
 	}
-	plugin.Start(
-		swap.Meta(),
-		swapPlugin,
-		os.Args[1],
-	)
+	os.Exit(runCommand(filepath.Base(os.Args[0]), os.Args[1:], os.Stdout, os.Stderr))
 }
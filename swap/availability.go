@@ -0,0 +1,93 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Data source availability metrics
+var availMetrics = []string{"available"}
+
+// probeSources checks if sources of basic swap metrics are accessible when plugin starts,
+// other sources are considered available until they fail
+func (swap *swapCollector) probeSources() {
+	ioFile := swap.src.ioOld
+	if swap.newIOfile {
+		ioFile = swap.src.ioNew
+	}
+	files := map[string]string{
+		ioPrefix:   ioFile,
+		devPrefix:  swap.src.perDev,
+		combPrefix: swap.src.combined,
+	}
+	for _, source := range errSources {
+		file, ok := files[source]
+		if !ok {
+			swap.setAvailable(swap.logEntry(), source, true, nil)
+			continue
+		}
+		fh, err := swap.src.Open(file)
+		if err != nil {
+			swap.setAvailable(swap.logEntry(), source, false, sourceError(file, err))
+			continue
+		}
+		fh.Close()
+		swap.setAvailable(swap.logEntry(), source, true, nil)
+	}
+}
+
+// setAvailable stores availability of data source and logs its change with given entry,
+// returned value tells if availability changed
+func (swap *swapCollector) setAvailable(entry *log.Entry, source string, available bool, err error) bool {
+	wasAvailable, known := swap.available[source]
+	swap.available[source] = available
+	swap.availStats[source+"/"+availMetrics[0]] = boolMetric(available)
+	if known && wasAvailable == available {
+		return false
+	}
+	fields := log.Fields{"source": source}
+	switch {
+	case available && known:
+		entry.WithFields(fields).Info("Data source became available")
+	case !available && known:
+		fields["error"] = err
+		entry.WithFields(fields).Warn("Data source became unavailable")
+	case !available:
+		fields["error"] = err
+		entry.WithFields(fields).Warn("Data source not available, it will be retried on every collection")
+	}
+	return true
+}
+
+// setSwapEnabled stores state of swap and logs its change with given entry
+func (swap *swapCollector) setSwapEnabled(entry *log.Entry, enabled bool) {
+	if swap.swapEnabled != nil && *swap.swapEnabled == enabled {
+		return
+	}
+	if enabled {
+		entry.Info("Swap is enabled")
+	} else {
+		entry.Warn("Total size of swap is zero, swap might be turned off")
+	}
+	swap.swapEnabled = &enabled
+}
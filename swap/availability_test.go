@@ -0,0 +1,94 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceAvailability(t *testing.T) {
+	fs := newMockFileSystem()
+	deleteMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	mts := []plugin.MetricType{
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "swap_enabled"),
+		},
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "source", "*", "available"),
+		},
+	}
+	Convey("plugin starts without data sources", t, func() {
+		So(swap, ShouldNotBeNil)
+		So(swap.available[ioPrefix], ShouldBeFalse)
+		So(swap.available[devPrefix], ShouldBeFalse)
+		So(swap.available[combPrefix], ShouldBeFalse)
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[3]+"/"+ns[4]] = metric.Data().(float64)
+		}
+		// swap_enabled is not reported as its source is not available
+		So(vals, ShouldNotContainKey, "all/swap_enabled")
+		So(vals["source/all"], ShouldEqual, 0)
+		So(vals["source/node"], ShouldEqual, 1)
+	})
+	Convey("data source which became available is used", t, func() {
+		createMockFilesWithErrors(fs,
+			"0", "0", "0",
+			"11111", "22222",
+			"33333", "44444",
+			"55555", "6666")
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[3]+"/"+ns[4]] = metric.Data().(float64)
+		}
+		So(vals["all/swap_enabled"], ShouldEqual, 0)
+		So(vals["source/all"], ShouldEqual, 1)
+		So(swap.available[combPrefix], ShouldBeTrue)
+		So(*swap.swapEnabled, ShouldBeFalse)
+	})
+	Convey("swap turned on", t, func() {
+		createMockFiles(fs)
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, 1)
+		So(*swap.swapEnabled, ShouldBeTrue)
+	})
+	Convey("data source which became unavailable is reported", t, func() {
+		delete(fs.Files, compMockFile)
+		changed := swap.setAvailable(swap.logEntry(), combPrefix, false, nil)
+		So(changed, ShouldBeTrue)
+		changed = swap.setAvailable(swap.logEntry(), combPrefix, false, nil)
+		So(changed, ShouldBeFalse)
+		So(swap.availStats["all/available"], ShouldEqual, 0)
+	})
+}
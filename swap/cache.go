@@ -0,0 +1,80 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"time"
+)
+
+// sourceRead holds outcome of read of data source, done is closed when read completes
+type sourceRead struct {
+	done   chan struct{}
+	readAt time.Time
+	err    error
+}
+
+// setCacheTTL sets freshness window in which result of data source read is shared, e.g. "5s"
+func (swap *swapCollector) setCacheTTL(ttl string) error {
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d < 0 {
+		return fmt.Errorf("%s is not a valid %s, expected non-negative duration, e.g. 5s", ttl, CacheTTLCfg)
+	}
+	swap.cacheTTL = d
+	return nil
+}
+
+// sharedRead returns read of source in progress or successful read which is still fresh at given time,
+// it must be called with stateMutex held, so concurrent collections share the read in progress
+func (swap *swapCollector) sharedRead(source string, now time.Time) *sourceRead {
+	if swap.cacheTTL == 0 {
+		return nil
+	}
+	read, ok := swap.reads[source]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-read.done:
+		// failures are not cached, so that source is read again by next collection
+		if read.err != nil || now.Sub(read.readAt) >= swap.cacheTTL {
+			return nil
+		}
+	default:
+	}
+	return read
+}
+
+// startRead registers read of source started at given time, so it is shared by concurrent collections
+func (swap *swapCollector) startRead(source string, readAt time.Time) *sourceRead {
+	read := &sourceRead{done: make(chan struct{}), readAt: readAt}
+	if swap.cacheTTL > 0 {
+		swap.reads[source] = read
+	}
+	return read
+}
+
+// finishRead stores outcome of read and releases collections waiting for it
+func (swap *swapCollector) finishRead(read *sourceRead, err error) {
+	read.err = err
+	close(read.done)
+}
@@ -0,0 +1,166 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceCache(t *testing.T) {
+	fs := newMockFileSystem()
+	usedMts := []plugin.MetricType{
+		plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "used_bytes")},
+	}
+	stat := func(swap *swapCollector, elems ...string) float64 {
+		ns := core.NewNamespace(append([]string{"intel", "procfs", "swap"}, elems...)...)
+		m, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{Namespace_: ns}})
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		return m[0].Data().(float64)
+	}
+	Convey("cache_ttl is configurable", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		node := cdata.NewNode()
+		node.AddItem(CacheTTLCfg, ctypes.ConfigValueStr{Value: "1m"})
+		_, err := swap.CollectMetrics([]plugin.MetricType{
+			plugin.MetricType{Namespace_: usedMts[0].Namespace(), Config_: node},
+		})
+		So(err, ShouldBeNil)
+		So(swap.cacheTTL.Seconds(), ShouldEqual, 60)
+		for _, ttl := range []string{"-1s", "soon"} {
+			swap := NewSwapCollectorFS(fs)
+			cfg := plugin.NewPluginConfigType()
+			cfg.AddItem(CacheTTLCfg, ctypes.ConfigValueStr{Value: ttl})
+			err := swap.setConfig(cfg)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "is not a valid cache_ttl")
+		}
+	})
+	Convey("sources are read on every collection by default", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics(usedMts)
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, (99999-1010)*1024)
+		createMockFilesWithErrors(fs,
+			"99999", "2020", "2020",
+			"11111", "22222",
+			"33333", "44444",
+			"55555", "6666")
+		m, err = swap.CollectMetrics(usedMts)
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, (99999-2020)*1024)
+		So(stat(swap, "plugin", "all", "cache_hits"), ShouldEqual, 0)
+		createMockFiles(fs)
+	})
+	Convey("fresh read is shared", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		So(swap.setCacheTTL("1h"), ShouldBeNil)
+		m, err := swap.CollectMetrics(usedMts)
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, (99999-1010)*1024)
+		bytes := stat(swap, "plugin", "all", "bytes_read")
+		createMockFilesWithErrors(fs,
+			"99999", "2020", "2020",
+			"11111", "22222",
+			"33333", "44444",
+			"55555", "6666")
+		m, err = swap.CollectMetrics(usedMts)
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, (99999-1010)*1024)
+		So(stat(swap, "plugin", "all", "cache_hits"), ShouldEqual, 1)
+		So(stat(swap, "plugin", "all", "bytes_read"), ShouldEqual, bytes)
+		createMockFiles(fs)
+	})
+	Convey("failed read is not cached", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		So(swap.setCacheTTL("1h"), ShouldBeNil)
+		delete(fs.Files, compMockFile)
+		for i := 0; i < 3; i++ {
+			m, err := swap.CollectMetrics(usedMts)
+			So(err, ShouldNotBeNil)
+			So(m, ShouldBeEmpty)
+		}
+		So(stat(swap, "errors", "all", "read_errors"), ShouldEqual, 3)
+		So(stat(swap, "plugin", "all", "cache_hits"), ShouldEqual, 0)
+		createMockFiles(fs)
+		m, err := swap.CollectMetrics(usedMts)
+		So(err, ShouldBeNil)
+		So(m[0].Data(), ShouldEqual, (99999-1010)*1024)
+	})
+	Convey("collection waiting for read which failed reads source itself", t, func() {
+		blocking := &blockingFileSystem{
+			FileSystem: fs,
+			err:        os.ErrPermission,
+			opened:     make(chan struct{}),
+			release:    make(chan struct{}),
+		}
+		swap := NewSwapCollectorFS(blocking)
+		So(swap.setCacheTTL("1h"), ShouldBeNil)
+		blocking.name = compMockFile
+		failedDone := make(chan error)
+		go func() {
+			_, err := swap.CollectMetrics(usedMts)
+			failedDone <- err
+		}()
+		<-blocking.opened
+		waitingDone := make(chan error)
+		go func() {
+			m, err := swap.CollectMetrics(usedMts)
+			if err == nil && m[0].Data() != float64((99999-1010)*1024) {
+				err = fmt.Errorf("unexpected used_bytes %v", m[0].Data())
+			}
+			waitingDone <- err
+		}()
+		// give the second collection time to find the read in progress
+		time.Sleep(100 * time.Millisecond)
+		close(blocking.release)
+		So(<-failedDone, ShouldNotBeNil)
+		So(<-waitingDone, ShouldBeNil)
+		So(stat(swap, "errors", "all", "read_errors"), ShouldEqual, 1)
+		So(stat(swap, "plugin", "all", "cache_hits"), ShouldEqual, 0)
+	})
+	Convey("concurrent collections read source once", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		So(swap.setCacheTTL("1h"), ShouldBeNil)
+		const tasks = 8
+		wg := &sync.WaitGroup{}
+		for i := 0; i < tasks; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				swap.CollectMetrics(mockMts[8:])
+			}()
+		}
+		wg.Wait()
+		So(stat(swap, "plugin", "all", "cache_hits"), ShouldEqual, tasks-1)
+	})
+}
@@ -0,0 +1,157 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConcurrentCollection(t *testing.T) {
+	fs := newMockFileSystem()
+	Convey("concurrent collections of many tasks", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		requests := [][]plugin.MetricType{
+			mockMts,
+			mockMts[4:8],
+			[]plugin.MetricType{
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "errors", "*", "read_errors")},
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "plugin", "*", "bytes_read")},
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "source", "*", "available")},
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "features", "vmstat_io")},
+			},
+			[]plugin.MetricType{
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "dummy", "free_bytes")},
+				plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "free_bytes")},
+			},
+		}
+		const tasks = 8
+		const collections = 50
+		errs := make(chan error, tasks*collections)
+		wg := &sync.WaitGroup{}
+		for i := 0; i < tasks; i++ {
+			wg.Add(1)
+			go func(mts []plugin.MetricType) {
+				defer wg.Done()
+				for j := 0; j < collections; j++ {
+					m, err := swap.CollectMetrics(mts)
+					if err != nil {
+						errs <- err
+						continue
+					}
+					if len(m) == 0 {
+						errs <- fmt.Errorf("no metrics collected for %s", mts[0].Namespace().String())
+					}
+				}
+			}(requests[i%len(requests)])
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < collections; j++ {
+				if _, err := swap.GetMetricTypes(plugin.NewPluginConfigType()); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+		m, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "errors", "device", "missing_metrics"),
+		}})
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		// every collection of one of the tasks requests missing device
+		So(m[0].Data(), ShouldEqual, tasks/len(requests)*collections)
+	})
+}
+
+// blockingFileSystem blocks first opening of given file until it is released,
+// the opening fails with err if it is set
+type blockingFileSystem struct {
+	FileSystem
+	name    string
+	err     error
+	once    sync.Once
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (fs *blockingFileSystem) Open(name string) (io.ReadCloser, error) {
+	if name == fs.name {
+		blocked := false
+		fs.once.Do(func() {
+			blocked = true
+			fs.opened <- struct{}{}
+			<-fs.release
+		})
+		if blocked && fs.err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: fs.err}
+		}
+	}
+	return fs.FileSystem.Open(name)
+}
+
+func TestCollectionNotBlockedByGather(t *testing.T) {
+	fs := &blockingFileSystem{
+		FileSystem: newMockFileSystem(),
+		opened:     make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	createZoneMockFile(fs.FileSystem.(*MapFileSystem), "4000")
+	swap := NewSwapCollectorFS(fs)
+	fs.name = zoneMockFile
+	Convey("collection goes on while data source of another one is read", t, func() {
+		zoneDone := make(chan error)
+		go func() {
+			_, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "zone", "*", "*", "low_pages"),
+			}})
+			zoneDone <- err
+		}()
+		<-fs.opened
+		combDone := make(chan error)
+		go func() {
+			_, err := swap.CollectMetrics(mockMts[8:])
+			combDone <- err
+		}()
+		select {
+		case err := <-combDone:
+			So(err, ShouldBeNil)
+		case <-time.After(5 * time.Second):
+			So("collection blocked by read of zoneinfo", ShouldBeEmpty)
+		}
+		close(fs.release)
+		So(<-zoneDone, ShouldBeNil)
+	})
+}
@@ -0,0 +1,338 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+var (
+	// Configured vs active swap metrics
+	cfgdMetrics = []string{"configured_count", "active_count", "missing_count", "unexpected_count",
+		"priority_mismatch_count", "inactive_files_count"}
+	// Kinds of fstab swap specifications and directories of src.disk they are resolved with
+	specLinks = map[string]string{
+		"UUID=":      "by-uuid",
+		"LABEL=":     "by-label",
+		"PARTUUID=":  "by-partuuid",
+		"PARTLABEL=": "by-partlabel",
+	}
+)
+
+// configuredSwap holds swap area configured to be activated at boot
+type configuredSwap struct {
+	spec     string
+	key      string
+	file     bool
+	priority string
+	source   string
+}
+
+// systemdUnitDirs returns directories of systemd system unit search path in root,
+// ordered from the highest precedence, see systemd.unit(5)
+func systemdUnitDirs(root string) []string {
+	dirs := []string{
+		"etc/systemd/system.control",
+		"run/systemd/system.control",
+		"run/systemd/transient",
+		"run/systemd/generator.early",
+		"etc/systemd/system",
+		"etc/systemd/system.attached",
+		"run/systemd/system",
+		"run/systemd/system.attached",
+		"run/systemd/generator",
+		"usr/local/lib/systemd/system",
+		"usr/lib/systemd/system",
+		"lib/systemd/system",
+		"run/systemd/generator.late",
+	}
+	for i, dir := range dirs {
+		dirs[i] = filepath.Join(root, dir)
+	}
+	return dirs
+}
+
+// getConfiguredMetrics compares swap areas configured in fstab and systemd swap units
+// with active swap devices found by getDevMetrics
+func getConfiguredMetrics(swap *swapCollector) error {
+	configured, err := readFstabSwaps(swap.src)
+	if err != nil {
+		return err
+	}
+	units, err := readSystemdSwaps(swap.src)
+	if err != nil {
+		return err
+	}
+	// systemd units take precedence over fstab entries for the same device
+	byKey := map[string]configuredSwap{}
+	for _, c := range append(configured, units...) {
+		byKey[c.key] = c
+	}
+	active := map[string]swapDevice{}
+	for _, device := range swap.devices {
+		for _, key := range activeSwapKeys(swap.src, device) {
+			active[key] = device
+		}
+	}
+	matched := map[string]bool{}
+	stats := map[string]float64{}
+	for _, c := range byKey {
+		device, ok := active[c.key]
+		if !ok {
+			if c.file {
+				stats[cfgdMetrics[5]]++
+			} else {
+				stats[cfgdMetrics[2]]++
+			}
+			continue
+		}
+		matched[device.path] = true
+		if c.priority != "" && !samePriority(c.priority, device.priority) {
+			stats[cfgdMetrics[4]]++
+		}
+	}
+	for _, device := range swap.devices {
+		if !matched[device.path] {
+			stats[cfgdMetrics[3]]++
+		}
+	}
+	stats[cfgdMetrics[0]] = float64(len(byKey))
+	stats[cfgdMetrics[1]] = float64(len(swap.devices))
+	for _, metric := range cfgdMetrics {
+		swap.cfgdStats[metric] = stats[metric]
+	}
+	return nil
+}
+
+// readFstabSwaps returns swap areas configured in src.fstab, entries marked as noauto
+// are not activated at boot and are skipped, missing fstab is treated as empty
+func readFstabSwaps(src *sources) ([]configuredSwap, error) {
+	fd, err := openSource(src, src.fstab)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, sourceError(src.fstab, err)
+	}
+	defer fd.Close()
+	swaps := []configuredSwap{}
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || fields[2] != "swap" {
+			continue
+		}
+		priority := ""
+		noauto := false
+		if len(fields) > 3 {
+			for _, opt := range strings.Split(fields[3], ",") {
+				switch {
+				case strings.HasPrefix(opt, "pri="):
+					priority = strings.TrimPrefix(opt, "pri=")
+				case opt == "noauto":
+					noauto = true
+				}
+			}
+		}
+		if noauto {
+			continue
+		}
+		swaps = append(swaps, newConfiguredSwap(src, swapstat.UnescapeOctal(fields[0]), priority, src.fstab))
+	}
+	return swaps, nil
+}
+
+// readSystemdSwaps returns swap areas configured by enabled systemd swap units,
+// unit in the first of src.systemdUnits directories overrides the same unit in others
+func readSystemdSwaps(src *sources) ([]configuredSwap, error) {
+	enabled := enabledSystemdUnits(src)
+	seen := map[string]bool{}
+	swaps := []configuredSwap{}
+	for _, dir := range src.systemdUnits {
+		entries, err := src.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			unit := entry.Name()
+			if !strings.HasSuffix(unit, ".swap") || seen[unit] || !enabled[unit] {
+				continue
+			}
+			seen[unit] = true
+			path := filepath.Join(dir, unit)
+			what, priority, err := parseSwapUnit(src, path)
+			if err != nil {
+				return nil, err
+			}
+			if what == "" {
+				continue
+			}
+			swaps = append(swaps, newConfiguredSwap(src, what, priority, path))
+		}
+	}
+	return swaps, nil
+}
+
+// enabledSystemdUnits returns names of units wanted or required by other units in any
+// of src.systemdUnits directories, e.g. linked in swap.target.wants by administrator,
+// by vendor or by generator such as systemd-fstab-generator
+func enabledSystemdUnits(src *sources) map[string]bool {
+	enabled := map[string]bool{}
+	for _, dir := range src.systemdUnits {
+		entries, err := src.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), ".wants") && !strings.HasSuffix(entry.Name(), ".requires") {
+				continue
+			}
+			units, err := src.ReadDir(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			for _, unit := range units {
+				enabled[unit.Name()] = true
+			}
+		}
+	}
+	return enabled
+}
+
+// parseSwapUnit returns What and priority (Priority or pri option) of [Swap] section of unit file
+func parseSwapUnit(fs FileSystem, path string) (string, string, error) {
+	fd, err := openSource(fs, path)
+	if err != nil {
+		return "", "", sourceError(path, err)
+	}
+	defer fd.Close()
+	what, priority := "", ""
+	section := ""
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line
+			continue
+		}
+		if section != "[Swap]" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "What":
+			what = value
+		case "Priority":
+			priority = value
+		case "Options":
+			for _, opt := range strings.Split(value, ",") {
+				if strings.HasPrefix(opt, "pri=") && priority == "" {
+					priority = strings.TrimPrefix(opt, "pri=")
+				}
+			}
+		}
+	}
+	return what, priority, nil
+}
+
+// newConfiguredSwap resolves swap specification (device path, UUID=, LABEL=, PARTUUID=,
+// PARTLABEL= or path of swap file) to key it can be matched with active swap device by
+func newConfiguredSwap(src *sources, spec string, priority string, source string) configuredSwap {
+	c := configuredSwap{spec: spec, key: spec, priority: priority, source: source}
+	for prefix, dir := range specLinks {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		value := strings.Trim(strings.TrimPrefix(spec, prefix), `"`)
+		c.key = prefix + value
+		link := filepath.Join(src.disk, dir, escapeUdev(value))
+		if resolved, err := src.EvalSymlinks(link); err == nil {
+			c.key = resolved
+		}
+		return c
+	}
+	if pathHasPrefix(spec, DevPathDir) {
+		if resolved, err := src.EvalSymlinks(src.devicePathInRoot(spec)); err == nil {
+			c.key = resolved
+		}
+		return c
+	}
+	c.file = true
+	return c
+}
+
+// activeSwapKeys returns keys active swap device can be matched with configured swap by
+func activeSwapKeys(src *sources, device swapDevice) []string {
+	keys := []string{device.path}
+	if device.kind == "partition" {
+		if resolved, err := src.EvalSymlinks(src.devicePathInRoot(device.path)); err == nil {
+			keys = append(keys, resolved)
+		}
+	}
+	if device.header != nil && device.header.uuid != "" {
+		keys = append(keys, "UUID="+device.header.uuid)
+	}
+	if device.header != nil && device.header.label != "" {
+		keys = append(keys, "LABEL="+device.header.label)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func samePriority(configured, active string) bool {
+	c, err1 := strconv.Atoi(configured)
+	a, err2 := strconv.Atoi(active)
+	if err1 != nil || err2 != nil {
+		return configured == active
+	}
+	return c == a
+}
+
+// escapeUdev encodes characters of label or UUID the way udev does in names of symlinks
+func escapeUdev(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			strings.IndexByte("#+-.:=@_", c) >= 0, c >= 0x80:
+			out = append(out, c)
+		default:
+			out = append(out, []byte(fmt.Sprintf(`\x%02x`, c))...)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,132 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const cfgdRootMockDir = "/host"
+
+func TestConfiguredMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createPriorityMockFiles(fs)
+	createConfiguredMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	cfg := plugin.NewPluginConfigType()
+	cfg.AddItem(RootPathCfg, ctypes.ConfigValueStr{Value: cfgdRootMockDir})
+	if err := swap.setConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	mts := []plugin.MetricType{}
+	for _, metric := range cfgdMetrics {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "configured", metric),
+		})
+	}
+	Convey("configured swap is compared with active swap devices", t, func() {
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 6)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().Strings()[4]] = metric.Data().(float64)
+		}
+		// UUID of sdb1, sdc1, sdz9 and swap file from fstab, sdd1 from systemd unit
+		So(vals["configured_count"], ShouldEqual, 5)
+		So(vals["active_count"], ShouldEqual, 5)
+		So(vals["missing_count"], ShouldEqual, 1)
+		// sdb2 and sdc2 are not configured
+		So(vals["unexpected_count"], ShouldEqual, 2)
+		So(vals["priority_mismatch_count"], ShouldEqual, 1)
+		So(vals["inactive_files_count"], ShouldEqual, 1)
+	})
+	Convey("units enabled by vendor or generated by systemd are configured", t, func() {
+		vendor := filepath.Join(cfgdRootMockDir, "lib", "systemd", "system")
+		generator := filepath.Join(cfgdRootMockDir, "run", "systemd", "generator")
+		vendorLink := filepath.Join(vendor, "swap.target.wants", "dev-sdb2.swap")
+		generatedUnit := filepath.Join(generator, "dev-sdc2.swap")
+		generatedLink := filepath.Join(generator, "swap.target.requires", "dev-sdc2.swap")
+		fs.Links[vendorLink] = filepath.Join(vendor, "dev-sdb2.swap")
+		fs.Files[generatedUnit] = "[Swap]\nWhat=/dev/sdc2\nOptions=pri=-2\n"
+		fs.Links[generatedLink] = generatedUnit
+		defer func() {
+			delete(fs.Links, vendorLink)
+			delete(fs.Files, generatedUnit)
+			delete(fs.Links, generatedLink)
+		}()
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().Strings()[4]] = metric.Data().(float64)
+		}
+		So(vals["configured_count"], ShouldEqual, 7)
+		So(vals["unexpected_count"], ShouldEqual, 0)
+	})
+	Convey("missing configuration sources are treated as empty", t, func() {
+		fs.Dirs["/empty"] = true
+		cfg := plugin.NewPluginConfigType()
+		cfg.AddItem(RootPathCfg, ctypes.ConfigValueStr{Value: "/empty"})
+		swap.initialized = false
+		So(swap.setConfig(cfg), ShouldBeNil)
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().Strings()[4]] = metric.Data().(float64)
+		}
+		So(vals["configured_count"], ShouldEqual, 0)
+		So(vals["unexpected_count"], ShouldEqual, 5)
+	})
+	Convey("udev escaping of labels", t, func() {
+		So(escapeUdev("my swap/1"), ShouldEqual, `my\x20swap\x2f1`)
+		So(escapeUdev("a-b_c.d"), ShouldEqual, "a-b_c.d")
+	})
+}
+
+func createConfiguredMockFiles(fs *MapFileSystem) {
+	units := filepath.Join(cfgdRootMockDir, "lib", "systemd", "system")
+	wants := filepath.Join(cfgdRootMockDir, "etc", "systemd", "system", "swap.target.wants")
+	for _, dev := range []string{"sdb1", "sdb2", "sdc1", "sdc2", "sdd1"} {
+		fs.Files[filepath.Join(DevPathDir, dev)] = ""
+	}
+	fs.Links["/dev/disk/by-uuid/0a1b2c3d-0000-4000-8000-000000000001"] = "../../sdb1"
+	fs.Files[filepath.Join(cfgdRootMockDir, "etc", "fstab")] =
+		"# <file system> <mount point> <type> <options> <dump> <pass>\n" +
+			"/dev/sda1 / ext4 defaults 0 1\n" +
+			"UUID=0a1b2c3d-0000-4000-8000-000000000001 none swap sw,pri=10 0 0\n" +
+			"/dev/sdc1 none swap sw,pri=3 0 0\n" +
+			"/dev/sdz9 none swap sw 0 0\n" +
+			"/dev/sdc2 none swap noauto 0 0\n" +
+			"/swapfile none swap sw 0 0\n"
+	fs.Files[filepath.Join(units, "dev-sdd1.swap")] =
+		"[Unit]\nDescription=Swap\n\n[Swap]\nWhat=/dev/sdd1\nPriority=-2\n"
+	fs.Files[filepath.Join(units, "dev-sdb2.swap")] = "[Swap]\nWhat=/dev/sdb2\n"
+	fs.Links[filepath.Join(wants, "dev-sdd1.swap")] = filepath.Join(units, "dev-sdd1.swap")
+}
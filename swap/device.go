@@ -0,0 +1,78 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"strings"
+)
+
+// getDevTags builds tags of swap devices found by getDevMetrics,
+// swap files are resolved to mount point, filesystem type and backing block device
+// and every device which is backed by block device gets tags describing storage media
+// and device-mapper stack
+func getDevTags(swap *swapCollector) error {
+	tags := map[string]map[string]string{}
+	var mounts []mountPoint
+	for _, device := range swap.devices {
+		devTags := map[string]string{tagDevicePath: device.path}
+		tags[device.name] = devTags
+		if device.header != nil && device.header.uuid != "" {
+			devTags[tagSwapUUID] = device.header.uuid
+		}
+		if device.header != nil && device.header.label != "" {
+			devTags[tagSwapLabel] = device.header.label
+		}
+		blockPath := ""
+		switch device.kind {
+		case "partition":
+			blockPath = device.path
+		case "file":
+			if mounts == nil {
+				var err error
+				mounts, err = readMountinfo(swap.src)
+				if err != nil {
+					return err
+				}
+			}
+			mount, ok := findMountPoint(mounts, device.path)
+			if !ok {
+				continue
+			}
+			devTags[tagMountPoint] = mount.path
+			devTags[tagFsType] = mount.fsType
+			if blockDev, ok := resolveMajMin(swap.src, mount.majMin); ok {
+				devTags[tagBlockDevice] = "/dev/" + blockDev
+			} else if strings.HasPrefix(mount.source, "/dev/") {
+				// e.g. btrfs reports anonymous device number, source is the real device then
+				devTags[tagBlockDevice] = mount.source
+			}
+			blockPath = devTags[tagBlockDevice]
+		}
+		if blockPath != "" {
+			blockDev := blockDeviceName(swap.src, swap.src.devicePathInRoot(blockPath))
+			addMediaTags(swap.src, devTags, blockDev)
+			addDMTags(swap.src, devTags, blockDev)
+		}
+	}
+	swap.devTags = tags
+	return nil
+}
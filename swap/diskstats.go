@@ -0,0 +1,230 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Size of sector used by block layer statistics
+	sectorSize = 512.0
+	// Number of block layer statistics used for swap device IO metrics
+	diskFields = 11
+)
+
+// Indexes of block layer statistics, as documented in Documentation/block/stat.txt
+const (
+	readIOs = iota
+	readMerges
+	readSectors
+	readTicks
+	writeIOs
+	writeMerges
+	writeSectors
+	writeTicks
+	inFlight
+	ioTicks
+	timeInQueue
+)
+
+var (
+	// Per swap partition block IO metrics
+	devIOMetrics = []string{"read_iops", "write_iops", "read_bytes_per_sec", "write_bytes_per_sec",
+		"read_await_ms", "write_await_ms", "queue_depth"}
+)
+
+// diskData holds historic block layer statistics of swap partition for rate calculation
+type diskData struct {
+	stats     [diskFields]float64
+	timestamp time.Time
+}
+
+// getDevIOMetrics calculates block IO metrics of swap partitions found by getDevMetrics,
+// statistics are read from src.diskstats or, if device is not listed there, from src.block
+func getDevIOMetrics(swap *swapCollector) error {
+	var diskstats map[string][diskFields]float64
+	seen := map[string]bool{}
+	// stats are built anew, so that devices which were turned off are not reported
+	devIOStats := map[string]float64{}
+	for _, device := range swap.devices {
+		if device.kind != "partition" {
+			continue
+		}
+		if diskstats == nil {
+			var err error
+			diskstats, err = readDiskstats(swap.src)
+			if err != nil {
+				return err
+			}
+		}
+		blockDev := blockDeviceName(swap.src, swap.src.devicePathInRoot(device.path))
+		stats, ok := diskstats[blockDev]
+		if !ok {
+			var err error
+			stats, ok, err = readBlockStat(swap.src, blockDev)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		seen[device.name] = true
+		now := time.Now()
+		old, ok := swap.diskHistory[device.name]
+		swap.diskHistory[device.name] = diskData{stats: stats, timestamp: now}
+		duration := now.Sub(old.timestamp).Seconds()
+		if !ok || duration <= 0 || counterReset(old.stats, stats) {
+			if ok && duration > 0 {
+				swap.logEntry().WithFields(log.Fields{"device": device.path, "block_device": blockDev}).
+					Debug("Block device statistics went backwards, rates are reset")
+				swap.src.countReset()
+			}
+			for _, metric := range devIOMetrics {
+				devIOStats[device.name+"/"+metric] = 0
+			}
+			continue
+		}
+		delta := func(i int) float64 { return stats[i] - old.stats[i] }
+		devIOStats[device.name+"/"+devIOMetrics[0]] = delta(readIOs) / duration
+		devIOStats[device.name+"/"+devIOMetrics[1]] = delta(writeIOs) / duration
+		devIOStats[device.name+"/"+devIOMetrics[2]] = delta(readSectors) * sectorSize / duration
+		devIOStats[device.name+"/"+devIOMetrics[3]] = delta(writeSectors) * sectorSize / duration
+		devIOStats[device.name+"/"+devIOMetrics[4]] = 0
+		if delta(readIOs) > 0 {
+			devIOStats[device.name+"/"+devIOMetrics[4]] = delta(readTicks) / delta(readIOs)
+		}
+		devIOStats[device.name+"/"+devIOMetrics[5]] = 0
+		if delta(writeIOs) > 0 {
+			devIOStats[device.name+"/"+devIOMetrics[5]] = delta(writeTicks) / delta(writeIOs)
+		}
+		// average queue size is time spent by requests in queue divided by elapsed time (both in ms)
+		devIOStats[device.name+"/"+devIOMetrics[6]] = delta(timeInQueue) / (duration * 1000)
+	}
+	for name := range swap.diskHistory {
+		if !seen[name] {
+			delete(swap.diskHistory, name)
+		}
+	}
+	swap.devIOStats = devIOStats
+	return nil
+}
+
+// blockDeviceName returns kernel name of block device, symlinks such as
+// /dev/mapper/NAME or /dev/disk/by-uuid/UUID are resolved if possible
+func blockDeviceName(fs FileSystem, path string) string {
+	if resolved, err := fs.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return filepath.Base(path)
+}
+
+// counterReset checks if any of cumulative statistics went backwards,
+// e.g. after 32 bit counter wrap or device re-creation
+func counterReset(old, cur [diskFields]float64) bool {
+	for i := range cur {
+		if i == inFlight {
+			continue
+		}
+		if cur[i] < old[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// readDiskstats returns block layer statistics of all devices listed in src.diskstats,
+// devices are not listed if the file is not available
+func readDiskstats(src *sources) (map[string][diskFields]float64, error) {
+	diskstats := map[string][diskFields]float64{}
+	fd, err := openSource(src, src.diskstats)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return diskstats, nil
+		}
+		return nil, sourceError(src.diskstats, err)
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3+diskFields {
+			if len(fields) > 0 {
+				src.skipLine()
+			}
+			continue
+		}
+		stats, err := parseBlockStat(fields[3:], fields[2], src.diskstats, lineNo)
+		if err != nil {
+			return nil, err
+		}
+		diskstats[fields[2]] = stats
+	}
+	return diskstats, nil
+}
+
+// readBlockStat returns block layer statistics of device from sysfs stat file
+func readBlockStat(src *sources, dev string) ([diskFields]float64, bool, error) {
+	path := filepath.Join(src.block, dev, "stat")
+	content, err := readSource(src, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return [diskFields]float64{}, false, nil
+		}
+		return [diskFields]float64{}, false, sourceError(path, err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) < diskFields {
+		field := fmt.Sprintf("Block device %s statistics field %d", dev, len(fields)+1)
+		return [diskFields]float64{}, false, parseError(path, 0, field, "", ErrMissingField)
+	}
+	stats, err := parseBlockStat(fields, dev, path, 0)
+	if err != nil {
+		return [diskFields]float64{}, false, err
+	}
+	return stats, true, nil
+}
+
+// parseBlockStat parses statistics of block device dev read from given line of file
+func parseBlockStat(fields []string, dev string, file string, lineNo int) ([diskFields]float64, error) {
+	var stats [diskFields]float64
+	for i := 0; i < diskFields; i++ {
+		val, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return stats, parseError(file, lineNo, fmt.Sprintf("Block device %s statistics field %d", dev, i+1), fields[i], err)
+		}
+		stats[i] = val
+	}
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	diskstatsMockFile = ProcPathDir + "/diskstats"
+	blockMockDir      = SysPathDir + "/class/block"
+)
+
+func TestDevIOMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createDiskstatsMockFiles(fs, "1000", "8000", "500", "2000", "16000", "3000", "9000")
+	swap := NewSwapCollectorFS(fs)
+	mts := []plugin.MetricType{}
+	for _, metric := range devIOMetrics {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", metric),
+		})
+	}
+	Convey("first collection has no rate available", t, func() {
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// 7 metrics for 2 partitions
+		So(len(m), ShouldEqual, 14)
+		for _, metric := range m {
+			So(metric.Data(), ShouldEqual, 0)
+		}
+	})
+	Convey("block IO is calculated between collections", t, func() {
+		// 100 reads of 400 kB taking 500 ms and 200 writes of 800 kB taking 3000 ms during 2s
+		createDiskstatsMockFiles(fs, "1100", "8800", "1000", "2200", "17600", "6000", "13000")
+		h := swap.diskHistory["dev_sda5"]
+		h.timestamp = time.Now().Add(-2 * time.Second)
+		swap.diskHistory["dev_sda5"] = h
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 14)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[4]+"/"+ns[5]] = metric.Data().(float64)
+		}
+		So(vals["dev_sda5/read_iops"], ShouldAlmostEqual, 50, 0.5)
+		So(vals["dev_sda5/write_iops"], ShouldAlmostEqual, 100, 1)
+		So(vals["dev_sda5/read_bytes_per_sec"], ShouldAlmostEqual, 204800, 2048)
+		So(vals["dev_sda5/write_bytes_per_sec"], ShouldAlmostEqual, 409600, 4096)
+		So(vals["dev_sda5/read_await_ms"], ShouldEqual, 5)
+		So(vals["dev_sda5/write_await_ms"], ShouldEqual, 15)
+		So(vals["dev_sda5/queue_depth"], ShouldAlmostEqual, 2, 0.02)
+		// sda6 statistics are read from sysfs and did not change
+		So(vals["dev_sda6/write_iops"], ShouldEqual, 0)
+	})
+	Convey("counter reset is not reported as rate", t, func() {
+		createDiskstatsMockFiles(fs, "10", "80", "5", "20", "160", "30", "90")
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		for _, metric := range m {
+			So(metric.Data(), ShouldEqual, 0)
+		}
+	})
+	Convey("device turned off is no longer reported", t, func() {
+		fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n/dev/sda5 partition 55555 6666 -1\n"
+		defer createMockFiles(fs)
+		all := append([]plugin.MetricType{}, mts...)
+		for _, metric := range []string{"used_bytes", "priority"} {
+			all = append(all, plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", metric),
+			})
+		}
+		m, err := swap.CollectMetrics(all)
+		So(err, ShouldBeNil)
+		// 7 IO metrics, used_bytes and priority of sda5
+		So(len(m), ShouldEqual, 9)
+		for _, metric := range m {
+			So(metric.Namespace()[4].Value, ShouldEqual, "dev_sda5")
+		}
+		So(swap.diskHistory, ShouldNotContainKey, "dev_sda6")
+	})
+	Convey("diskstats with errors", t, func() {
+		createDiskstatsMockFiles(fs, "not-an-int", "80", "5", "20", "160", "30", "90")
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Block device sda5 statistics")
+		So(m, ShouldBeEmpty)
+	})
+}
+
+func createDiskstatsMockFiles(fs *MapFileSystem, reads, readSectors, readTicks, writes, writeSectors, writeTicks, queueTime string) {
+	fs.Files[diskstatsMockFile] = "   8       0 sda 5000 10 90000 4000 7000 20 80000 9000 0 12000 13000 0 0 0 0\n" +
+		"   8       5 sda5 " + reads + " 0 " + readSectors + " " + readTicks + " " + writes + " 0 " +
+		writeSectors + " " + writeTicks + " 1 4000 " + queueTime + " 0 0 0 0\n" +
+		"   8       7 sda7 1 2 3\n"
+	fs.Files[filepath.Join(blockMockDir, "sda6", "stat")] =
+		"     100        0     800       10      200        0     1600       20        0       30       30\n"
+}
@@ -0,0 +1,106 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Tags describing device-mapper device holding swap
+	tagDMName    = "dm_name"
+	tagLVMVG     = "lvm_vg"
+	tagLVMLV     = "lvm_lv"
+	tagCryptName = "crypt_name"
+	tagEncrypted = "encrypted"
+
+	// Prefixes of device-mapper uuid set by LVM and cryptsetup
+	lvmUUIDPrefix   = "LVM-"
+	cryptUUIDPrefix = "CRYPT-"
+)
+
+// addDMTags tags swap device backed by block device dev with names of device-mapper
+// device, LVM volume group and logical volume or crypt mapping, and whether any
+// device in the stack below swap is encrypted
+func addDMTags(src *sources, tags map[string]string, dev string) {
+	if _, err := src.Stat(filepath.Join(src.block, dev)); err != nil {
+		return
+	}
+	name := readSysfsValue(src, filepath.Join(src.block, dev, "dm", "name"))
+	uuid := readSysfsValue(src, filepath.Join(src.block, dev, "dm", "uuid"))
+	if name != "" {
+		tags[tagDMName] = name
+		if strings.HasPrefix(uuid, lvmUUIDPrefix) {
+			if vg, lv, ok := splitLVMName(name); ok {
+				tags[tagLVMVG] = vg
+				tags[tagLVMLV] = lv
+			}
+		}
+	}
+	if cryptName, ok := findCrypt(src, dev, 0); ok {
+		tags[tagCryptName] = cryptName
+		tags[tagEncrypted] = "true"
+	} else {
+		tags[tagEncrypted] = "false"
+	}
+}
+
+// findCrypt looks for crypt mapping in block device dev and devices below it,
+// e.g. swap on LVM logical volume placed on LUKS encrypted partition
+func findCrypt(src *sources, dev string, depth int) (string, bool) {
+	if depth > maxStackDepth {
+		return "", false
+	}
+	uuid := readSysfsValue(src, filepath.Join(src.block, dev, "dm", "uuid"))
+	if strings.HasPrefix(uuid, cryptUUIDPrefix) {
+		return readSysfsValue(src, filepath.Join(src.block, dev, "dm", "name")), true
+	}
+	slaves, err := src.ReadDir(filepath.Join(src.block, dev, "slaves"))
+	if err != nil {
+		return "", false
+	}
+	for _, slave := range slaves {
+		if name, ok := findCrypt(src, slave.Name(), depth+1); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// splitLVMName splits device-mapper name of LVM logical volume into volume group
+// and logical volume names, dashes within the names are doubled by LVM
+func splitLVMName(name string) (string, string, bool) {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '-' {
+			continue
+		}
+		if i+1 < len(name) && name[i+1] == '-' {
+			i++
+			continue
+		}
+		vg := strings.Replace(name[:i], "--", "-", -1)
+		lv := strings.Replace(name[i+1:], "--", "-", -1)
+		return vg, lv, vg != "" && lv != ""
+	}
+	return "", "", false
+}
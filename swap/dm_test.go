@@ -0,0 +1,111 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var sysDMMockDir = SysPathDir
+
+func TestDMTags(t *testing.T) {
+	fs := newMockFileSystem()
+	createDMMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	Convey("device-mapper swap devices are resolved", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		tags := map[string]map[string]string{}
+		for _, metric := range m {
+			tags[metric.Namespace()[4].Value] = metric.Tags()
+		}
+		So(tags["dev_sda5"][tagEncrypted], ShouldEqual, "false")
+		So(tags["dev_sda5"][tagDMName], ShouldBeEmpty)
+		// plain LVM logical volume
+		So(tags["dev_mapper_my--vg-swap"][tagDMName], ShouldEqual, "my--vg-swap")
+		So(tags["dev_mapper_my--vg-swap"][tagLVMVG], ShouldEqual, "my-vg")
+		So(tags["dev_mapper_my--vg-swap"][tagLVMLV], ShouldEqual, "swap")
+		So(tags["dev_mapper_my--vg-swap"][tagEncrypted], ShouldEqual, "false")
+		// LUKS mapping
+		So(tags["dev_mapper_cryptswap"][tagDMName], ShouldEqual, "cryptswap")
+		So(tags["dev_mapper_cryptswap"][tagCryptName], ShouldEqual, "cryptswap")
+		So(tags["dev_mapper_cryptswap"][tagEncrypted], ShouldEqual, "true")
+		// LVM logical volume on LUKS mapping
+		So(tags["dev_mapper_secure-swap"][tagLVMVG], ShouldEqual, "secure")
+		So(tags["dev_mapper_secure-swap"][tagLVMLV], ShouldEqual, "swap")
+		So(tags["dev_mapper_secure-swap"][tagCryptName], ShouldEqual, "luks-root")
+		So(tags["dev_mapper_secure-swap"][tagEncrypted], ShouldEqual, "true")
+	})
+	Convey("device-mapper swap devices are named by mapper name", t, func() {
+		So(deviceName(swap.src, "/dev/dm-0", deviceIDPath, nil), ShouldEqual, "dev_mapper_my--vg-swap")
+		So(deviceName(swap.src, "/dev/dm-3", deviceIDUUID, nil), ShouldEqual, "dev_mapper_secure-swap")
+		So(deviceName(swap.src, "/dev/sda5", deviceIDPath, nil), ShouldEqual, "dev_sda5")
+		So(deviceName(swap.src, "/swapfile", deviceIDPath, nil), ShouldEqual, "swapfile")
+	})
+	Convey("LVM names are split", t, func() {
+		vg, lv, ok := splitLVMName("vg0-lv--swap--1")
+		So(ok, ShouldBeTrue)
+		So(vg, ShouldEqual, "vg0")
+		So(lv, ShouldEqual, "lv-swap-1")
+		_, _, ok = splitLVMName("novolume")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func createDMMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sda5 partition 55555 6666 -1\n" +
+		"/dev/dm-0 partition 77777 8888 -2\n" +
+		"/dev/dm-1 partition 77777 8888 -3\n" +
+		"/dev/dm-3 partition 77777 8888 -4\n"
+	classBlock := filepath.Join(sysDMMockDir, "class", "block")
+	devs := map[string]struct{ name, uuid, slave string }{
+		"sda5": {"", "", ""},
+		"sda6": {"", "", ""},
+		"dm-0": {"my--vg-swap", "LVM-Ecp3LpxyEoRjuxvIDu2GnD1bM0bWJa2Gz9RvNlS4Ny9qlLcdBK0mLfSi2SXPFhrb", "sda6"},
+		"dm-1": {"cryptswap", "CRYPT-PLAIN-cryptswap", "sda6"},
+		"dm-2": {"luks-root", "CRYPT-LUKS2-6e0ebb0bb42f4c8a9fb1ba3f6fb0e5b2-luks-root", "sda6"},
+		"dm-3": {"secure-swap", "LVM-yQ4yEXUwRfR0SZgIobxnAPjkXZNbhhdDWkiAyDNCiPKCXiL2cZXbUVCiewwXuB3J", "dm-2"},
+	}
+	for dev, dm := range devs {
+		dir := filepath.Join(sysDMMockDir, "devices", "virtual", "block", dev)
+		fs.Dirs[dir] = true
+		fs.Links[filepath.Join(classBlock, dev)] = dir
+		if dm.name == "" {
+			continue
+		}
+		writeMockValue(fs, filepath.Join(dir, "dm", "name"), dm.name)
+		writeMockValue(fs, filepath.Join(dir, "dm", "uuid"), dm.uuid)
+		fs.Links[filepath.Join(dir, "slaves", dm.slave)] = filepath.Join(classBlock, dm.slave)
+	}
+}
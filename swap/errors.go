@@ -0,0 +1,119 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+// ErrMissingField is held by ParseError when data source does not have
+// expected field, e.g. stat file is truncated; it is shared with swapstat parsers
+var ErrMissingField = swapstat.ErrMissingField
+
+// SourceUnavailableError is returned when data source cannot be opened or read,
+// Err holds the underlying error, e.g. *os.PathError with ENOENT or EACCES
+type SourceUnavailableError struct {
+	File string
+	Err  error
+}
+
+func (e *SourceUnavailableError) Error() string {
+	return fmt.Sprintf("Failed to open following file for reading: %s (%s)", e.File, causeMessage(e.Err))
+}
+
+// Cause returns the underlying error
+func (e *SourceUnavailableError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error
+func (e *SourceUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// NotExist checks if data source does not exist, e.g. feature is missing in running kernel
+func (e *SourceUnavailableError) NotExist() bool {
+	return os.IsNotExist(e.Err)
+}
+
+// Permission checks if plugin is not allowed to read data source
+func (e *SourceUnavailableError) Permission() bool {
+	return os.IsPermission(e.Err)
+}
+
+// ParseError is returned when value read from data source is malformed,
+// Line is 1-based number of line holding the value or 0 if not applicable
+// and Err holds the underlying error, e.g. *strconv.NumError
+type ParseError struct {
+	File  string
+	Line  int
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s is not a number: %s", e.Field, e.Value)
+	if e.Err == ErrMissingField {
+		msg = e.Field + " is missing"
+	}
+	switch {
+	case e.File != "" && e.Line > 0:
+		msg += fmt.Sprintf(" (%s:%d)", e.File, e.Line)
+	case e.File != "":
+		msg += fmt.Sprintf(" (%s)", e.File)
+	}
+	return msg
+}
+
+// Cause returns the underlying error
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func sourceError(file string, err error) error {
+	return &SourceUnavailableError{File: file, Err: err}
+}
+
+func parseError(file string, line int, field string, value string, err error) error {
+	return &ParseError{File: file, Line: line, Field: field, Value: value, Err: err}
+}
+
+// causeMessage returns message of the underlying error without path,
+// which is already part of SourceUnavailableError message
+func causeMessage(err error) string {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err.Error()
+	}
+	if err == nil {
+		return "unknown error"
+	}
+	return err.Error()
+}
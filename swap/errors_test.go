@@ -0,0 +1,106 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTypedErrors(t *testing.T) {
+	fs := newMockFileSystem()
+	swap := NewSwapCollectorFS(fs)
+	Convey("missing data source", t, func() {
+		deleteMockFiles(fs)
+		err := getCombinedMetrics(swap.src, map[string]float64{})
+		So(err, ShouldNotBeNil)
+		srcErr, ok := err.(*SourceUnavailableError)
+		So(ok, ShouldBeTrue)
+		So(srcErr.File, ShouldEqual, compMockFile)
+		So(srcErr.NotExist(), ShouldBeTrue)
+		So(srcErr.Permission(), ShouldBeFalse)
+		So(os.IsNotExist(srcErr.Cause()), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "Failed to open following file for reading")
+		So(err.Error(), ShouldContainSubstring, "no such file or directory")
+	})
+	Convey("data source which cannot be read", t, func() {
+		createMockFiles(fs)
+		fs.Errors[perDevMockFile] = os.ErrPermission
+		err := getDevMetrics(swap)
+		So(err, ShouldNotBeNil)
+		srcErr, ok := err.(*SourceUnavailableError)
+		So(ok, ShouldBeTrue)
+		So(srcErr.NotExist(), ShouldBeFalse)
+		So(srcErr.Permission(), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "permission denied")
+	})
+	Convey("malformed data", t, func() {
+		createMockFilesWithErrors(fs,
+			"not-an-int", "1010", "2020",
+			"11111", "22222",
+			"33333", "44444",
+			"55555", "not-an-int")
+		err := getCombinedMetrics(swap.src, map[string]float64{})
+		So(err, ShouldNotBeNil)
+		parseErr, ok := err.(*ParseError)
+		So(ok, ShouldBeTrue)
+		So(parseErr.File, ShouldEqual, compMockFile)
+		So(parseErr.Line, ShouldEqual, 6)
+		So(parseErr.Field, ShouldEqual, "SwapTotal")
+		So(parseErr.Value, ShouldEqual, "not-an-int")
+		_, ok = parseErr.Cause().(*strconv.NumError)
+		So(ok, ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "SwapTotal is not a number: not-an-int ("+compMockFile+":6)")
+
+		err = getDevMetrics(swap)
+		parseErr, ok = err.(*ParseError)
+		So(ok, ShouldBeTrue)
+		So(parseErr.Line, ShouldEqual, 2)
+		So(parseErr.Field, ShouldEqual, "Used swap size for dev_sda5")
+	})
+	Convey("truncated data", t, func() {
+		fs := &MapFileSystem{Files: map[string]string{
+			"/proc/45/stat":             "45 (kswapd0) S 2 0 0 0 -1 2129984 0 0 0 0 100\n",
+			"/proc/46/stat":             "46 (kswapd1 S 2 0\n",
+			blockMockDir + "/sda5/stat": "1 2 3\n",
+		}}
+		_, _, err := readProcessTimes(fs, "/proc/45/stat")
+		parseErr, ok := err.(*ParseError)
+		So(ok, ShouldBeTrue)
+		So(parseErr.Field, ShouldEqual, "stime")
+		So(parseErr.Cause(), ShouldEqual, ErrMissingField)
+		So(err.Error(), ShouldEqual, "stime is missing (/proc/45/stat)")
+		_, _, err = readProcessTimes(fs, "/proc/46/stat")
+		parseErr, ok = err.(*ParseError)
+		So(ok, ShouldBeTrue)
+		So(parseErr.Field, ShouldEqual, "comm")
+		_, _, err = readBlockStat(defaultSources(fs), "sda5")
+		parseErr, ok = err.(*ParseError)
+		So(ok, ShouldBeTrue)
+		So(parseErr.File, ShouldEqual, blockMockDir+"/sda5/stat")
+		So(parseErr.Field, ShouldEqual, "Block device sda5 statistics field 4")
+		So(parseErr.Cause(), ShouldEqual, ErrMissingField)
+	})
+}
@@ -0,0 +1,90 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Sources of per device metrics which can fail independently of src.perDev
+	devIOSource   = "device_io"
+	devTagsSource = "device_tags"
+)
+
+var (
+	// Sources of metrics failures are counted for
+	errSources = []string{ioPrefix, devPrefix, devIOSource, devTagsSource, combPrefix, cfgdPrefix, hibPrefix,
+		featPrefix, prioPrefix, nodePrefix, zonePrefix, kswapdPrefix}
+	// Collection errors metrics
+	errMetrics = []string{"read_errors", "missing_metrics"}
+	// Per device metrics read from other source than src.perDev, these are not reported
+	// when their source failed instead of reporting values of previous collection
+	metricSource = func() map[string]string {
+		sources := map[string]string{}
+		for _, metric := range devIOMetrics {
+			sources[metric] = devIOSource
+		}
+		return sources
+	}()
+)
+
+// newErrStats returns collection errors counters of all sources set to zero
+func newErrStats() map[string]float64 {
+	stats := map[string]float64{}
+	for _, source := range errSources {
+		for _, metric := range errMetrics {
+			stats[source+"/"+metric] = 0
+		}
+	}
+	return stats
+}
+
+// sourceFailed counts failure of reading data source, which is logged once when
+// the source becomes unavailable; metrics of the source are not reported by this collection
+func (swap *swapCollector) sourceFailed(entry *log.Entry, source string, err error) {
+	if !swap.setAvailable(entry, source, false, err) {
+		entry.WithFields(log.Fields{"source": source}).Debug(err)
+	}
+	swap.countError(source, errMetrics[0])
+}
+
+// partsFailed counts failed reads of parts of data source, which were skipped by gathering of the source
+func (swap *swapCollector) partsFailed(source string, count uint64) {
+	for i := uint64(0); i < count; i++ {
+		swap.countError(source, errMetrics[0])
+	}
+}
+
+// metricMissing logs and counts requested metric which is not available
+func (swap *swapCollector) metricMissing(entry *log.Entry, source string, err error) {
+	entry.WithFields(log.Fields{"source": source}).Warn(err)
+	swap.countError(source, errMetrics[1])
+}
+
+func (swap *swapCollector) countError(source string, metric string) {
+	key := source + "/" + metric
+	// counters exist only for known sources, unknown group comes from malformed request
+	if _, ok := swap.errStats[key]; ok {
+		swap.errStats[key]++
+	}
+}
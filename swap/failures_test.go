@@ -0,0 +1,93 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCollectionErrors(t *testing.T) {
+	fs := newMockFileSystem()
+	swap := NewSwapCollectorFS(fs)
+	errMts := []plugin.MetricType{
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "errors", "*", "read_errors"),
+		},
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "errors", "*", "missing_metrics"),
+		},
+	}
+	Convey("error counters of all sources are reported", t, func() {
+		m, err := swap.CollectMetrics(errMts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 2*len(errSources))
+		for _, metric := range m {
+			So(metric.Data(), ShouldEqual, 0)
+		}
+	})
+	Convey("missing metric does not drop the others", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "dummy", "free_bytes"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "used_bytes"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "dummy"),
+			},
+		}
+		m, err := swap.CollectMetrics(append(mts, errMts...))
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[3]+"/"+ns[4]+"/"+ns[len(ns)-1]] = metric.Data().(float64)
+		}
+		So(vals, ShouldContainKey, "all/used_bytes/used_bytes")
+		So(vals["errors/device/missing_metrics"], ShouldEqual, 1)
+		So(vals["errors/all/missing_metrics"], ShouldEqual, 1)
+		So(vals["errors/all/read_errors"], ShouldEqual, 0)
+	})
+	Convey("failed device IO source does not report stale rates", t, func() {
+		createDiskstatsMockFiles(fs, "not-an-int", "80", "5", "20", "160", "30", "90")
+		swap.devIOStats["dev_sda5/read_iops"] = 42
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "read_iops"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		for _, metric := range m {
+			So(metric.Namespace()[5].Value, ShouldEqual, "used_bytes")
+		}
+		So(swap.errStats["device_io/read_errors"], ShouldEqual, 1)
+	})
+}
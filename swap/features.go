@@ -0,0 +1,260 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// Kernel feature metrics
+	featMetrics = []string{"kernel_major", "kernel_minor", "zswap_enabled", "noswap", "cgroup_memory_enabled",
+		"cgroup_unified_hierarchy", "vmstat_io"}
+)
+
+// kernelFeatures holds capabilities of running kernel relevant to swap
+type kernelFeatures struct {
+	release  string
+	major    int
+	minor    int
+	zswap    bool
+	noswap   bool
+	memcg    bool
+	unified  bool
+	vmstatIO bool
+}
+
+// kernelConfig holds feature defaults of configuration of kernel of given release,
+// configuration does not change while kernel runs, so it is read once per release
+type kernelConfig struct {
+	read    bool
+	release string
+	zswap   bool
+	memcg   bool
+}
+
+// load reads configuration of kernel of given release unless it was already read
+func (c *kernelConfig) load(src *sources, release string) {
+	if c.read && c.release == release {
+		return
+	}
+	kconfig := readKernelConfig(src, release)
+	*c = kernelConfig{
+		read:    true,
+		release: release,
+		zswap:   kconfig["CONFIG_ZSWAP"] == "y" && kconfig["CONFIG_ZSWAP_DEFAULT_ON"] == "y",
+		memcg:   kconfig["CONFIG_MEMCG"] == "y",
+	}
+}
+
+// detectFeatures finds capabilities of running kernel from its release, command line
+// and configuration, which is kept in kconfig; runtime state (sysfs, procfs) takes
+// precedence over command line, which takes precedence over configuration defaults
+func detectFeatures(src *sources, kconfig *kernelConfig) kernelFeatures {
+	f := kernelFeatures{release: readSysfsValue(src, src.osRelease)}
+	versionKnown := false
+	f.major, f.minor, versionKnown = parseKernelRelease(f.release)
+	cmdline, err := readCmdline(src)
+	if err != nil {
+		cmdline = map[string]string{}
+	}
+	kconfig.load(src, f.release)
+
+	if enabled := readSysfsValue(src, src.zswapEnabled); enabled != "" {
+		f.zswap = parseKernelBool(enabled)
+	} else if enabled, ok := cmdline["zswap.enabled"]; ok {
+		f.zswap = parseKernelBool(enabled)
+	} else {
+		f.zswap = kconfig.zswap
+	}
+
+	_, f.noswap = cmdline["noswap"]
+
+	if enabled, ok := memcgEnabled(src); ok {
+		f.memcg = enabled
+	} else if hasListItem(cmdline["cgroup_disable"], "memory") {
+		f.memcg = false
+	} else if hasListItem(cmdline["cgroup_enable"], "memory") {
+		f.memcg = true
+	} else {
+		f.memcg = kconfig.memcg
+	}
+
+	if unified, ok := cmdline["systemd.unified_cgroup_hierarchy"]; ok {
+		// parameter without value enables unified hierarchy
+		f.unified = unified == "" || parseKernelBool(unified)
+	} else {
+		f.unified = cgroup2Mounted(src)
+	}
+
+	// swap IO counters are in vmstat since 2.6, probe the file only when release is unknown
+	if versionKnown {
+		f.vmstatIO = f.major > 2 || (f.major == 2 && f.minor >= 6)
+	} else {
+		f.vmstatIO = fileOK(src, src.ioNew)
+	}
+	return f
+}
+
+// setStats stores feature flags in dest
+func (f kernelFeatures) setStats(dest map[string]float64) {
+	dest[featMetrics[0]] = float64(f.major)
+	dest[featMetrics[1]] = float64(f.minor)
+	dest[featMetrics[2]] = boolMetric(f.zswap)
+	dest[featMetrics[3]] = boolMetric(f.noswap)
+	dest[featMetrics[4]] = boolMetric(f.memcg)
+	dest[featMetrics[5]] = boolMetric(f.unified)
+	dest[featMetrics[6]] = boolMetric(f.vmstatIO)
+}
+
+// parseKernelRelease returns major and minor version of kernel release, e.g. "4.15.0-142-generic"
+func parseKernelRelease(release string) (int, int, bool) {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minorS := parts[1]
+	if i := strings.IndexFunc(minorS, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minorS = minorS[:i]
+	}
+	minor, err := strconv.Atoi(minorS)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// parseKernelBool parses boolean value the way kernel module parameters do
+func parseKernelBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "y", "yes", "true", "on":
+		return true
+	}
+	return false
+}
+
+func hasListItem(list string, item string) bool {
+	for _, i := range strings.Split(list, ",") {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// memcgEnabled returns state of memory controller listed in src.cgroups,
+// where every line has format: SUBSYS_NAME HIERARCHY NUM_CGROUPS ENABLED
+func memcgEnabled(src *sources) (bool, bool) {
+	fd, err := openSource(src, src.cgroups)
+	if err != nil {
+		return false, false
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 4 && fields[0] == "memory" {
+			return fields[3] == "1", true
+		}
+	}
+	return false, false
+}
+
+// cgroup2Mounted checks if unified cgroup hierarchy is mounted at /sys/fs/cgroup
+func cgroup2Mounted(src *sources) bool {
+	mounts, err := readMountinfo(src)
+	if err != nil {
+		return false
+	}
+	for _, mount := range mounts {
+		if mount.path == "/sys/fs/cgroup" && mount.fsType == "cgroup2" {
+			return true
+		}
+	}
+	return false
+}
+
+// readKernelConfig returns options of running kernel configuration read from src.kernelConfig
+// or config file of given release in src.boot, empty map if neither is available
+func readKernelConfig(src *sources, release string) map[string]string {
+	kconfig := map[string]string{}
+	var r io.Reader
+	if fd, err := openSource(src, src.kernelConfig); err == nil {
+		defer fd.Close()
+		gz, err := gzip.NewReader(fd)
+		if err != nil {
+			return kconfig
+		}
+		defer gz.Close()
+		r = gz
+	} else if release != "" {
+		fd, err := openSource(src, filepath.Join(src.boot, "config-"+release))
+		if err != nil {
+			return kconfig
+		}
+		defer fd.Close()
+		r = fd
+	} else {
+		return kconfig
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 {
+			kconfig[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return kconfig
+}
+
+// setFeatures detects kernel features and selects data sources accordingly
+func (swap *swapCollector) setFeatures() {
+	features := detectFeatures(swap.src, &swap.kconfig)
+	if features != swap.features {
+		ioFile := swap.src.ioOld
+		if features.vmstatIO {
+			ioFile = swap.src.ioNew
+		}
+		swap.logEntry().WithFields(log.Fields{"release": features.release, "source": ioPrefix, "file": ioFile}).
+			Debug("Kernel features detected, IO data source selected")
+	}
+	swap.features = features
+	swap.newIOfile = features.vmstatIO
+	features.setStats(swap.featStats)
+}
@@ -0,0 +1,140 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFeatureMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	src := defaultSources(fs)
+	createFeatureMockFiles(fs, src)
+	swap := NewSwapCollectorFS(fs)
+	mts := []plugin.MetricType{}
+	for _, metric := range featMetrics {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "features", metric),
+		})
+	}
+	collect := func() map[string]float64 {
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 7)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().Strings()[4]] = metric.Data().(float64)
+		}
+		return vals
+	}
+	Convey("features are taken from kernel configuration", t, func() {
+		vals := collect()
+		So(vals["kernel_major"], ShouldEqual, 4)
+		So(vals["kernel_minor"], ShouldEqual, 15)
+		So(vals["zswap_enabled"], ShouldEqual, 1)
+		So(vals["noswap"], ShouldEqual, 0)
+		So(vals["cgroup_memory_enabled"], ShouldEqual, 1)
+		So(vals["cgroup_unified_hierarchy"], ShouldEqual, 0)
+		So(vals["vmstat_io"], ShouldEqual, 1)
+	})
+	Convey("kernel configuration is read once per release", t, func() {
+		delete(fs.Files, src.kernelConfig)
+		vals := collect()
+		So(vals["zswap_enabled"], ShouldEqual, 1)
+		So(vals["cgroup_memory_enabled"], ShouldEqual, 1)
+		// configuration of other release is neither running nor installed
+		writeMockValue(fs, src.osRelease, "4.15.0-143-generic")
+		vals = collect()
+		So(vals["zswap_enabled"], ShouldEqual, 0)
+		So(vals["cgroup_memory_enabled"], ShouldEqual, 0)
+		createFeatureMockFiles(fs, src)
+		vals = collect()
+		So(vals["zswap_enabled"], ShouldEqual, 1)
+	})
+	Convey("kernel command line overrides configuration", t, func() {
+		writeMockValue(fs, src.cmdline, "root=/dev/sda1 zswap.enabled=0 noswap cgroup_disable=cpu,memory systemd.unified_cgroup_hierarchy=1")
+		vals := collect()
+		So(vals["zswap_enabled"], ShouldEqual, 0)
+		So(vals["noswap"], ShouldEqual, 1)
+		So(vals["cgroup_memory_enabled"], ShouldEqual, 0)
+		So(vals["cgroup_unified_hierarchy"], ShouldEqual, 1)
+	})
+	Convey("runtime state overrides kernel command line", t, func() {
+		writeMockValue(fs, src.zswapEnabled, "Y")
+		writeMockValue(fs, src.cgroups, "#subsys_name\thierarchy\tnum_cgroups\tenabled\ncpu\t2\t1\t1\nmemory\t0\t1\t1")
+		writeMockValue(fs, src.cmdline, "root=/dev/sda1 zswap.enabled=0 cgroup_disable=memory")
+		writeMockValue(fs, src.mountinfo, "25 1 0:22 / /sys/fs/cgroup rw,nosuid - cgroup2 cgroup2 rw")
+		vals := collect()
+		So(vals["zswap_enabled"], ShouldEqual, 1)
+		So(vals["cgroup_memory_enabled"], ShouldEqual, 1)
+		So(vals["cgroup_unified_hierarchy"], ShouldEqual, 1)
+	})
+	Convey("old kernel selects old IO data source", t, func() {
+		writeMockValue(fs, src.osRelease, "2.4.37")
+		vals := collect()
+		So(vals["vmstat_io"], ShouldEqual, 0)
+		So(swap.newIOfile, ShouldBeFalse)
+	})
+	Convey("unknown kernel release falls back to available IO data source", t, func() {
+		delete(fs.Files, src.osRelease)
+		vals := collect()
+		So(vals["kernel_major"], ShouldEqual, 0)
+		So(vals["vmstat_io"], ShouldEqual, 1)
+		delete(fs.Files, ioNewMockFile)
+		vals = collect()
+		So(vals["vmstat_io"], ShouldEqual, 0)
+		createMockFiles(fs)
+	})
+	Convey("kernel release parsing", t, func() {
+		major, minor, ok := parseKernelRelease("5.10.0-21-amd64")
+		So(ok, ShouldBeTrue)
+		So(major, ShouldEqual, 5)
+		So(minor, ShouldEqual, 10)
+		major, minor, ok = parseKernelRelease("3.10rc1")
+		So(ok, ShouldBeTrue)
+		So(minor, ShouldEqual, 10)
+		_, _, ok = parseKernelRelease("unknown")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func createFeatureMockFiles(fs *MapFileSystem, src *sources) {
+	writeMockValue(fs, src.osRelease, "4.15.0-142-generic")
+	writeMockValue(fs, src.cmdline, "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet")
+	writeMockValue(fs, src.mountinfo, "25 1 0:22 / /sys/fs/cgroup ro,nosuid - tmpfs tmpfs ro,mode=755")
+	// installed kernel configuration is ignored when running one is available
+	writeMockValue(fs, filepath.Join(src.boot, "config-4.15.0-142-generic"), "CONFIG_ZSWAP=n\nCONFIG_MEMCG=n")
+	fs.Dirs[filepath.Dir(src.zswapEnabled)] = true
+	config := &bytes.Buffer{}
+	gz := gzip.NewWriter(config)
+	gz.Write([]byte("#\n# Automatically generated file; DO NOT EDIT.\n#\nCONFIG_SWAP=y\n" +
+		"CONFIG_MEMCG=y\nCONFIG_ZSWAP=y\nCONFIG_ZSWAP_DEFAULT_ON=y\n# CONFIG_HIBERNATION is not set\n"))
+	gz.Close()
+	fs.Files[src.kernelConfig] = config.String()
+}
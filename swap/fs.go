@@ -0,0 +1,327 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxSymlinks is number of symlinks followed while resolving path, as by Linux
+const maxSymlinks = 40
+
+// FileSystem is file system data sources are read from; names are absolute paths
+// derived from configured procfs, sysfs, devtmpfs and root filesystem paths and errors
+// are *os.PathError, so that os.IsNotExist and os.IsPermission apply to them
+type FileSystem interface {
+	// Open opens file for reading, symlinks are followed
+	Open(name string) (io.ReadCloser, error)
+	// Stat returns description of file, symlinks are followed
+	Stat(name string) (os.FileInfo, error)
+	// ReadDir returns entries of directory sorted by name, symlinks are not followed for entries
+	ReadDir(name string) ([]os.FileInfo, error)
+	// EvalSymlinks returns path with all symlinks resolved
+	EvalSymlinks(name string) (string, error)
+}
+
+// OSFileSystem reads data sources from file system of the host
+var OSFileSystem FileSystem = osFileSystem{}
+
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (osFileSystem) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+// DirFileSystem reads data sources from snapshot of host file system copied to dir,
+// e.g. /proc of the host is read from dir/proc; absolute symlinks are resolved within dir
+func DirFileSystem(dir string) FileSystem {
+	return dirFileSystem{root: dir}
+}
+
+type dirFileSystem struct {
+	root string
+}
+
+func (d dirFileSystem) Open(name string) (io.ReadCloser, error) {
+	resolved, err := d.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: pathErrorCause(err)}
+	}
+	return os.Open(filepath.Join(d.root, resolved))
+}
+
+func (d dirFileSystem) Stat(name string) (os.FileInfo, error) {
+	resolved, err := d.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: pathErrorCause(err)}
+	}
+	return os.Stat(filepath.Join(d.root, resolved))
+}
+
+func (d dirFileSystem) ReadDir(name string) ([]os.FileInfo, error) {
+	resolved, err := d.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: pathErrorCause(err)}
+	}
+	return ioutil.ReadDir(filepath.Join(d.root, resolved))
+}
+
+func (d dirFileSystem) EvalSymlinks(name string) (string, error) {
+	return evalSymlinks(name, func(path string) (string, bool, error) {
+		info, err := os.Lstat(filepath.Join(d.root, path))
+		if err != nil {
+			return "", false, err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		target, err := os.Readlink(filepath.Join(d.root, path))
+		return target, true, err
+	})
+}
+
+// MapFileSystem holds data sources in memory, e.g. to test formats of various kernels;
+// directories holding listed paths exist implicitly
+type MapFileSystem struct {
+	// Content of regular files by path
+	Files map[string]string
+	// Targets of symlinks by path, absolute targets are resolved within the file system
+	Links map[string]string
+	// Directories without any files, links or directories in them
+	Dirs map[string]bool
+	// Errors returned on opening files by path, e.g. os.ErrPermission
+	Errors map[string]error
+}
+
+// Open opens file for reading, symlinks are followed
+func (m *MapFileSystem) Open(name string) (io.ReadCloser, error) {
+	resolved, err := m.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: pathErrorCause(err)}
+	}
+	if err, ok := m.Errors[resolved]; ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	content, ok := m.Files[resolved]
+	if !ok {
+		// only directories are left
+		return nil, &os.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+// Stat returns description of file, symlinks are followed
+func (m *MapFileSystem) Stat(name string) (os.FileInfo, error) {
+	resolved, err := m.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: pathErrorCause(err)}
+	}
+	return m.lstat(resolved), nil
+}
+
+// ReadDir returns entries of directory sorted by name, symlinks are not followed for entries
+func (m *MapFileSystem) ReadDir(name string) ([]os.FileInfo, error) {
+	resolved, err := m.EvalSymlinks(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: pathErrorCause(err)}
+	}
+	if !m.isDir(resolved) {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: syscall.ENOTDIR}
+	}
+	prefix := strings.TrimSuffix(resolved, "/") + "/"
+	children := map[string]bool{}
+	for _, paths := range []map[string]bool{m.paths(m.Files), m.paths(m.Links), m.Dirs} {
+		for path := range paths {
+			if strings.HasPrefix(path, prefix) {
+				children[strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)[0]] = true
+			}
+		}
+	}
+	names := []string{}
+	for child := range children {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	entries := []os.FileInfo{}
+	for _, child := range names {
+		entries = append(entries, m.lstat(prefix+child))
+	}
+	return entries, nil
+}
+
+// EvalSymlinks returns path with all symlinks resolved
+func (m *MapFileSystem) EvalSymlinks(name string) (string, error) {
+	return evalSymlinks(name, func(path string) (string, bool, error) {
+		if target, ok := m.Links[path]; ok {
+			return target, true, nil
+		}
+		if _, ok := m.Files[path]; ok || m.isDir(path) {
+			return "", false, nil
+		}
+		return "", false, &os.PathError{Op: "lstat", Path: path, Err: syscall.ENOENT}
+	})
+}
+
+// lstat returns description of existing path, symlinks are not followed
+func (m *MapFileSystem) lstat(path string) os.FileInfo {
+	info := fileInfo{name: filepath.Base(path), mode: 0444}
+	if content, ok := m.Files[path]; ok {
+		info.size = int64(len(content))
+	} else if _, ok := m.Links[path]; ok {
+		info.mode = os.ModeSymlink | 0777
+	} else {
+		info.mode = os.ModeDir | 0555
+	}
+	return info
+}
+
+// isDir checks if path is a directory, explicitly or by holding other paths
+func (m *MapFileSystem) isDir(path string) bool {
+	if path == "/" || m.Dirs[path] {
+		return true
+	}
+	prefix := path + "/"
+	for _, paths := range []map[string]bool{m.paths(m.Files), m.paths(m.Links), m.Dirs} {
+		for p := range paths {
+			if strings.HasPrefix(p, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *MapFileSystem) paths(entries map[string]string) map[string]bool {
+	paths := map[string]bool{}
+	for path := range entries {
+		paths[path] = true
+	}
+	return paths
+}
+
+// TarFileSystem reads snapshot of host file system from tar archive into memory,
+// paths of archive are relative to root of the file system, e.g. proc/swaps
+func TarFileSystem(r io.Reader) (*MapFileSystem, error) {
+	m := &MapFileSystem{Files: map[string]string{}, Links: map[string]string{}, Dirs: map[string]bool{}}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join("/", header.Name)
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			content, err := ioutil.ReadAll(archive)
+			if err != nil {
+				return nil, err
+			}
+			m.Files[path] = string(content)
+		case tar.TypeSymlink:
+			m.Links[path] = header.Linkname
+		case tar.TypeDir:
+			m.Dirs[path] = true
+		}
+	}
+}
+
+// evalSymlinks resolves symlinks of absolute path component by component, readlink returns
+// target of given path and true if it is a symlink; absolute targets are resolved from "/"
+func evalSymlinks(name string, readlink func(path string) (string, bool, error)) (string, error) {
+	resolved := "/"
+	rest := strings.Split(filepath.Clean("/"+name), "/")
+	links := 0
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		path := filepath.Join(resolved, part)
+		target, isLink, err := readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !isLink {
+			resolved = path
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", &os.PathError{Op: "lstat", Path: name, Err: syscall.ELOOP}
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// pathErrorCause returns error of path operation without the path
+func pathErrorCause(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// fileInfo describes file of MapFileSystem
+type fileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() interface{}   { return nil }
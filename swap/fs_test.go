@@ -0,0 +1,182 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileSystems(t *testing.T) {
+	Convey("map file system", t, func() {
+		fs := &MapFileSystem{
+			Files: map[string]string{
+				"/sys/devices/virtual/block/dm-0/dm/name": "vg-swap\n",
+				"/sys/devices/pci/block/sda/sda2/size":    "1024\n",
+			},
+			Links: map[string]string{
+				"/sys/class/block/dm-0":          "../../devices/virtual/block/dm-0",
+				"/sys/class/block/sda2":          "/sys/devices/pci/block/sda/sda2",
+				"/dev/disk/by-uuid/0a1b":         "../../dm-0",
+				"/sys/class/block/loop":          "loop",
+				"/sys/class/block/missing":       "../../devices/missing",
+				"/sys/devices/pci/block/sda/sdz": "sda2",
+			},
+			Dirs: map[string]bool{"/sys/power": true},
+		}
+		content, err := readSource(fs, "/sys/class/block/dm-0/dm/name")
+		So(err, ShouldBeNil)
+		So(string(content), ShouldEqual, "vg-swap\n")
+		resolved, err := fs.EvalSymlinks("/sys/class/block/sda2/../sda2/size")
+		So(err, ShouldBeNil)
+		So(resolved, ShouldEqual, "/sys/devices/pci/block/sda/sda2/size")
+		resolved, err = fs.EvalSymlinks("/sys/devices/pci/block/sda/sdz")
+		So(err, ShouldBeNil)
+		So(resolved, ShouldEqual, "/sys/devices/pci/block/sda/sda2")
+		_, err = fs.EvalSymlinks("/sys/class/block/loop")
+		So(err, ShouldNotBeNil)
+		_, err = fs.Open("/sys/class/block/missing/size")
+		So(os.IsNotExist(err), ShouldBeTrue)
+		_, err = readSource(fs, "/proc/swaps")
+		So(os.IsNotExist(err), ShouldBeTrue)
+		info, err := fs.Stat("/sys/class/block/dm-0")
+		So(err, ShouldBeNil)
+		So(info.IsDir(), ShouldBeTrue)
+		info, err = fs.Stat("/sys/power")
+		So(err, ShouldBeNil)
+		So(info.IsDir(), ShouldBeTrue)
+		entries, err := fs.ReadDir("/sys/class/block")
+		So(err, ShouldBeNil)
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+			So(entry.Mode()&os.ModeSymlink, ShouldNotEqual, 0)
+		}
+		So(names, ShouldResemble, []string{"dm-0", "loop", "missing", "sda2"})
+		entries, err = fs.ReadDir("/sys/class/block/sda2")
+		So(err, ShouldBeNil)
+		So(len(entries), ShouldEqual, 1)
+		So(entries[0].Name(), ShouldEqual, "size")
+		So(entries[0].Size(), ShouldEqual, 5)
+		_, err = fs.ReadDir("/sys/devices/pci/block/sda/sda2/size")
+		So(err, ShouldNotBeNil)
+		So(diskTransport(defaultSources(fs), "xyz0"), ShouldEqual, "unknown")
+	})
+	Convey("directory file system", t, func() {
+		dir, _ := ioutil.TempDir("", "swap-fs")
+		defer os.RemoveAll(dir)
+		os.MkdirAll(filepath.Join(dir, "proc"), 0755)
+		os.MkdirAll(filepath.Join(dir, "dev", "disk", "by-label"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "proc", "swaps"), []byte("Filename Type Size Used Priority\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "dev", "sda2"), []byte{}, 0644)
+		// absolute link is resolved within directory, not on the host
+		os.Symlink("/dev/sda2", filepath.Join(dir, "dev", "disk", "by-label", "swap"))
+		fs := DirFileSystem(dir)
+		content, err := readSource(fs, "/proc/swaps")
+		So(err, ShouldBeNil)
+		So(string(content), ShouldStartWith, "Filename")
+		resolved, err := fs.EvalSymlinks("/dev/disk/by-label/swap")
+		So(err, ShouldBeNil)
+		So(resolved, ShouldEqual, "/dev/sda2")
+		_, err = fs.Stat("/dev/disk/by-label/swap")
+		So(err, ShouldBeNil)
+		entries, err := fs.ReadDir("/dev/disk/by-label")
+		So(err, ShouldBeNil)
+		So(len(entries), ShouldEqual, 1)
+		_, err = fs.Open("/proc/meminfo")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+	Convey("tar file system", t, func() {
+		buf := &bytes.Buffer{}
+		archive := tar.NewWriter(buf)
+		archive.WriteHeader(&tar.Header{Name: "proc/", Typeflag: tar.TypeDir, Mode: 0755})
+		content := "Filename Type Size Used Priority\n"
+		archive.WriteHeader(&tar.Header{Name: "proc/swaps", Typeflag: tar.TypeReg, Mode: 0444, Size: int64(len(content))})
+		archive.Write([]byte(content))
+		archive.WriteHeader(&tar.Header{Name: "./sys/class/block/sda2", Typeflag: tar.TypeSymlink, Linkname: "../../../proc"})
+		archive.WriteHeader(&tar.Header{Name: "sys/power/", Typeflag: tar.TypeDir, Mode: 0755})
+		archive.Close()
+		fs, err := TarFileSystem(buf)
+		So(err, ShouldBeNil)
+		data, err := readSource(fs, "/sys/class/block/sda2/swaps")
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, content)
+		info, err := fs.Stat("/sys/power")
+		So(err, ShouldBeNil)
+		So(info.IsDir(), ShouldBeTrue)
+		_, err = TarFileSystem(bytes.NewBufferString("not a tar archive"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestCollectorFileSystem(t *testing.T) {
+	mts := []plugin.MetricType{
+		plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes")},
+		plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "free_bytes")},
+		plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "io", "in_pages_per_sec")},
+		plugin.MetricType{Namespace_: core.NewNamespace("intel", "procfs", "swap", "features", "vmstat_io")},
+	}
+	Convey("collectors read their own file systems", t, func() {
+		// swap file with escaped space in path and IO counters of 2.4 kernel in stat
+		old := NewSwapCollectorFS(&MapFileSystem{Files: map[string]string{
+			ProcPathDir + "/sys/kernel/osrelease": "2.4.37\n",
+			perDevMockFile:                        "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n/swap\\040file\tfile\t1024\t512\t-1\n",
+			compMockFile:                          "SwapTotal: 1024 kB\nSwapFree: 512 kB\n",
+			ioOldMockFile:                         "cpu 1 2 3 4\npage 100 200\n",
+		}})
+		recent := NewSwapCollectorFS(&MapFileSystem{Files: map[string]string{
+			ProcPathDir + "/sys/kernel/osrelease": "4.19.0\n",
+			perDevMockFile:                        "Filename Type Size Used Priority\n/dev/sda2 partition 2048 0 -2\n",
+			compMockFile:                          "SwapTotal: 2048 kB\nSwapFree: 2048 kB\n",
+			ioNewMockFile:                         "pswpin 1\npswpout 2\n",
+		}})
+		m, err := old.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().String()] = metric.Data().(float64)
+		}
+		So(vals, ShouldResemble, map[string]float64{
+			"/intel/procfs/swap/device/swap%20file/used_bytes": 512 * 1024,
+			"/intel/procfs/swap/all/free_bytes":                512 * 1024,
+			"/intel/procfs/swap/io/in_pages_per_sec":           vals["/intel/procfs/swap/io/in_pages_per_sec"],
+			"/intel/procfs/swap/features/vmstat_io":            0,
+		})
+		m, err = recent.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals = map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().String()] = metric.Data().(float64)
+		}
+		So(vals["/intel/procfs/swap/device/dev_sda2/used_bytes"], ShouldEqual, 0)
+		So(vals["/intel/procfs/swap/all/free_bytes"], ShouldEqual, 2048*1024)
+		So(vals["/intel/procfs/swap/features/vmstat_io"], ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,183 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+const (
+	// Magic of Linux swap area (version 1), placed at the end of the first page
+	swapMagic = "SWAPSPACE2"
+	// Magic of obsolete Linux swap area (version 0), not supported by kernels since 2.5
+	swapMagicOld = "SWAP-SPACE"
+	// Offset of swap header info, following the boot sector area
+	swapInfoOffset = 1024
+	// Largest page size swap area might be created with
+	maxPageSize = 65536
+
+	// Tags describing swap area header
+	tagSwapUUID  = "swap_uuid"
+	tagSwapLabel = "swap_label"
+)
+
+var (
+	// Page sizes swap area might be created with
+	pageSizes = []int{4096, 8192, 16384, 32768, 65536}
+	// Per device swap header metrics
+	headerMetrics = []string{"header_version", "header_last_page", "header_bad_pages", "header_size_bytes",
+		"header_size_mismatch"}
+)
+
+// swapHeader holds fields of Linux swap area header
+type swapHeader struct {
+	pageSize int
+	version  uint32
+	lastPage uint32
+	badPages uint32
+	uuid     string
+	label    string
+	obsolete bool
+}
+
+// cachedHeader holds swap header of device together with its entry in src.perDev
+// and identity of the device, header is read again only when one of these changes
+type cachedHeader struct {
+	kind     string
+	size     uint64
+	identity string
+	header   *swapHeader
+}
+
+// swapHeader returns header of swap device listed in src.perDev, nil if it is not available;
+// header is read only if device is new or changed since the previous gather, it is kept in headers
+func (swap *swapCollector) swapHeader(entry swapstat.SwapDevice, headers map[string]cachedHeader) *swapHeader {
+	path := swap.src.devicePathInRoot(entry.Name)
+	identity := deviceIdentity(swap.src, path)
+	cached, ok := swap.headers[entry.Name]
+	if !ok || cached.kind != entry.Type || cached.size != entry.Size || cached.identity != identity {
+		header, err := readSwapHeader(swap.src, path)
+		if err != nil {
+			// header is available only for readable swap devices
+			header = nil
+		}
+		cached = cachedHeader{kind: entry.Type, size: entry.Size, identity: identity, header: header}
+	}
+	headers[entry.Name] = cached
+	return cached.header
+}
+
+// deviceIdentity returns device and inode numbers of swap device or file, so that other device
+// turned on at the same path is told apart, empty if they are not provided by file system
+func deviceIdentity(fs FileSystem, path string) string {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return ""
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d:%d", stat.Dev, stat.Ino, stat.Rdev)
+}
+
+// readSwapHeader parses header of swap area placed in device or file with given path
+func readSwapHeader(fs FileSystem, path string) (*swapHeader, error) {
+	fd, err := openSource(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	if cap(*buf) < maxPageSize {
+		*buf = make([]byte, maxPageSize)
+	}
+	n, err := io.ReadFull(fd, (*buf)[:maxPageSize])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return parseSwapHeader((*buf)[:n])
+}
+
+// parseSwapHeader parses swap header from the beginning of swap area, see union swap_header in
+// include/linux/swap.h; page size of the area is found by position of the magic
+func parseSwapHeader(buf []byte) (*swapHeader, error) {
+	for _, pageSize := range pageSizes {
+		if len(buf) < pageSize {
+			break
+		}
+		magic := string(buf[pageSize-len(swapMagic) : pageSize])
+		if magic == swapMagicOld {
+			return &swapHeader{pageSize: pageSize, obsolete: true}, nil
+		}
+		if magic != swapMagic {
+			continue
+		}
+		info := buf[swapInfoOffset:]
+		var order binary.ByteOrder = binary.LittleEndian
+		// swap area might have been created on machine of other endianness
+		if order.Uint32(info[0:4]) != 1 && binary.BigEndian.Uint32(info[0:4]) == 1 {
+			order = binary.BigEndian
+		}
+		header := &swapHeader{
+			pageSize: pageSize,
+			version:  order.Uint32(info[0:4]),
+			lastPage: order.Uint32(info[4:8]),
+			badPages: order.Uint32(info[8:12]),
+			label:    string(bytes.TrimRight(info[28:44], "\x00")),
+		}
+		if uuid := info[12:28]; !bytes.Equal(uuid, make([]byte, 16)) {
+			header.uuid = fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+		}
+		return header, nil
+	}
+	return nil, fmt.Errorf("Swap area signature not found")
+}
+
+// setHeaderMetrics stores metrics of swap header of device dev with size (in kB) listed
+// in src.perDev, metrics are removed if header is not available
+func setHeaderMetrics(dest map[string]float64, dev string, header *swapHeader, size float64) {
+	if header == nil || header.obsolete {
+		for _, metric := range headerMetrics {
+			delete(dest, dev+"/"+metric)
+		}
+		return
+	}
+	pageSize := float64(header.pageSize)
+	dest[dev+"/"+headerMetrics[0]] = float64(header.version)
+	dest[dev+"/"+headerMetrics[1]] = float64(header.lastPage)
+	dest[dev+"/"+headerMetrics[2]] = float64(header.badPages)
+	dest[dev+"/"+headerMetrics[3]] = (float64(header.lastPage) + 1) * pageSize
+	// kernel uses all pages up to last_page except header page and bad pages
+	expected := (float64(header.lastPage) - float64(header.badPages)) * pageSize / 1024
+	dest[dev+"/"+headerMetrics[4]] = 0
+	if expected != size {
+		dest[dev+"/"+headerMetrics[4]] = 1
+	}
+}
@@ -0,0 +1,154 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	swapFileMockFile = "/tmp/swapfile_test"
+	hdrDevMockDir    = DevPathDir
+)
+
+func TestSwapHeader(t *testing.T) {
+	fs := newMockFileSystem()
+	createHeaderMockFiles(fs)
+	mts := []plugin.MetricType{}
+	for _, metric := range headerMetrics {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", metric),
+		})
+	}
+	Convey("swap headers are parsed", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// swap file and sda5 have readable headers, sda6 is not accessible
+		So(len(m), ShouldEqual, 10)
+		vals := map[string]float64{}
+		tags := map[string]map[string]string{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[4]+"/"+ns[5]] = metric.Data().(float64)
+			tags[ns[4]] = metric.Tags()
+		}
+		So(vals["tmp_swapfile%5Ftest/header_version"], ShouldEqual, 1)
+		So(vals["tmp_swapfile%5Ftest/header_last_page"], ShouldEqual, 255)
+		So(vals["tmp_swapfile%5Ftest/header_bad_pages"], ShouldEqual, 0)
+		So(vals["tmp_swapfile%5Ftest/header_size_bytes"], ShouldEqual, 256*4096)
+		So(vals["tmp_swapfile%5Ftest/header_size_mismatch"], ShouldEqual, 0)
+		So(tags["tmp_swapfile%5Ftest"][tagSwapUUID], ShouldEqual, "0a3407de-014b-458b-b5c1-848e92a327a3")
+		So(tags["tmp_swapfile%5Ftest"][tagSwapLabel], ShouldEqual, "swapfile")
+		// sda5 has bad pages and is smaller than the header claims
+		So(vals["dev_sda5/header_bad_pages"], ShouldEqual, 2)
+		So(vals["dev_sda5/header_size_mismatch"], ShouldEqual, 1)
+		So(tags["dev_sda5"][tagSwapUUID], ShouldBeEmpty)
+	})
+	Convey("swap file is named by UUID from its header", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		cfg := plugin.NewPluginConfigType()
+		cfg.AddItem(DeviceIDCfg, ctypes.ConfigValueStr{Value: deviceIDUUID})
+		So(swap.setConfig(cfg), ShouldBeNil)
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		names := []string{}
+		for _, metric := range m {
+			names = append(names, metric.Namespace()[4].Value)
+		}
+		So(names, ShouldContain, "0a3407de-014b-458b-b5c1-848e92a327a3")
+		So(names, ShouldContain, "dev_sda5")
+	})
+	Convey("header is read again only when swap device changes", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		_, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// header is rewritten while the device stays listed the same way
+		writeSwapHeaderMock(fs, filepath.Join(hdrDevMockDir, "sda5"), 20000, 3, make([]byte, 16), "")
+		defer createHeaderMockFiles(fs)
+		badPages := func() float64 {
+			m, err := swap.CollectMetrics(mts[2:3])
+			So(err, ShouldBeNil)
+			for _, metric := range m {
+				if metric.Namespace()[4].Value == "dev_sda5" {
+					return metric.Data().(float64)
+				}
+			}
+			return -1
+		}
+		So(badPages(), ShouldEqual, 2)
+		fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+			"/dev/sda5 partition 55551 6666 -1\n"
+		So(badPages(), ShouldEqual, 3)
+		So(swap.headers, ShouldContainKey, "/dev/sda5")
+		So(swap.headers, ShouldNotContainKey, swapFileMockFile)
+	})
+	Convey("headers of other formats", t, func() {
+		buf := make([]byte, 16384)
+		copy(buf[16384-10:], swapMagic)
+		binary.BigEndian.PutUint32(buf[1024:], 1)
+		binary.BigEndian.PutUint32(buf[1028:], 1000)
+		header, err := parseSwapHeader(buf)
+		So(err, ShouldBeNil)
+		So(header.pageSize, ShouldEqual, 16384)
+		So(header.version, ShouldEqual, 1)
+		So(header.lastPage, ShouldEqual, 1000)
+		buf = make([]byte, 4096)
+		copy(buf[4096-10:], swapMagicOld)
+		header, err = parseSwapHeader(buf)
+		So(err, ShouldBeNil)
+		So(header.obsolete, ShouldBeTrue)
+		_, err = parseSwapHeader(make([]byte, 8192))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "signature not found")
+	})
+}
+
+func createHeaderMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sda5 partition 55555 6666 -1\n" +
+		"/dev/sda6 partition 77777 8888 -2\n" +
+		swapFileMockFile + " file 1020 0 -3\n"
+	// tags of swap file are built from its mount point
+	fs.Files[mountinfoMockFile] = "28 1 8:2 / / rw,relatime shared:1 - ext4 /dev/sda2 rw\n"
+	writeSwapHeaderMock(fs, swapFileMockFile, 255, 0,
+		[]byte{0x0a, 0x34, 0x07, 0xde, 0x01, 0x4b, 0x45, 0x8b, 0xb5, 0xc1, 0x84, 0x8e, 0x92, 0xa3, 0x27, 0xa3}, "swapfile")
+	writeSwapHeaderMock(fs, filepath.Join(hdrDevMockDir, "sda5"), 20000, 2, make([]byte, 16), "")
+}
+
+func writeSwapHeaderMock(fs *MapFileSystem, path string, lastPage, badPages uint32, uuid []byte, label string) {
+	buf := make([]byte, 4096)
+	binary.LittleEndian.PutUint32(buf[1024:], 1)
+	binary.LittleEndian.PutUint32(buf[1028:], lastPage)
+	binary.LittleEndian.PutUint32(buf[1032:], badPages)
+	copy(buf[1036:], uuid)
+	copy(buf[1052:], label)
+	copy(buf[4096-10:], swapMagic)
+	fs.Files[path] = string(buf)
+}
@@ -0,0 +1,197 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+// Hibernation readiness metrics
+var hibMetrics = []string{"resume_configured", "resume_device_active", "resume_free_bytes", "image_size_bytes",
+	"image_headroom_bytes", "mem_total_headroom_bytes", "ready"}
+
+// getHibernationMetrics checks if swap device selected to hold hibernation image is active
+// and has enough free space for the image, it uses devices and tags found by getDevMetrics
+// and getDevTags
+func getHibernationMetrics(swap *swapCollector) error {
+	resumeDev, resumeSpec, offset, err := readResumeDevice(swap.src)
+	if err != nil {
+		return err
+	}
+	imageSize, err := readImageSize(swap.src)
+	if err != nil {
+		return err
+	}
+	memTotal, err := readMemTotal(swap.src)
+	if err != nil {
+		return err
+	}
+	configured := resumeDev != "" || resumeSpec != ""
+	active := false
+	free := 0.0
+	if configured {
+		for _, device := range swap.devices {
+			if isResumeDevice(swap, device, resumeDev, resumeSpec, offset) {
+				active = true
+				free = (device.size - device.used) * 1024.0
+				break
+			}
+		}
+	}
+	stats := map[string]float64{
+		hibMetrics[0]: boolMetric(configured),
+		hibMetrics[1]: boolMetric(active),
+		hibMetrics[2]: free,
+		hibMetrics[3]: imageSize,
+		hibMetrics[4]: free - imageSize,
+		hibMetrics[5]: free - memTotal,
+		hibMetrics[6]: boolMetric(active && free >= imageSize),
+	}
+	for metric, val := range stats {
+		swap.hibStats[metric] = val
+	}
+	return nil
+}
+
+// readResumeDevice returns kernel name of block device set in src.powerResume or, if not set,
+// the one given by resume= parameter of kernel command line, when the latter cannot be resolved
+// to block device the specification itself is returned, e.g. UUID=... of inactive device;
+// the returned offset is resume_offset= parameter used when image is written to swap file
+func readResumeDevice(src *sources) (string, string, string, error) {
+	resumeDev := ""
+	majMin := readSysfsValue(src, src.powerResume)
+	if majMin != "" && majMin != "0:0" {
+		if dev, ok := resolveMajMin(src, majMin); ok {
+			resumeDev = dev
+		}
+	}
+	cmdline, err := readCmdline(src)
+	if err != nil {
+		return "", "", "", err
+	}
+	spec := cmdline["resume"]
+	offset := cmdline["resume_offset"]
+	if resumeDev != "" || spec == "" {
+		return resumeDev, "", offset, nil
+	}
+	for prefix, dir := range specLinks {
+		if strings.HasPrefix(spec, prefix) {
+			value := strings.Trim(strings.TrimPrefix(spec, prefix), `"`)
+			if resolved, err := src.EvalSymlinks(filepath.Join(src.disk, dir, escapeUdev(value))); err == nil {
+				return filepath.Base(resolved), "", offset, nil
+			}
+			return "", prefix + value, offset, nil
+		}
+	}
+	if pathHasPrefix(spec, DevPathDir) {
+		if resolved, err := src.EvalSymlinks(src.devicePathInRoot(spec)); err == nil {
+			return filepath.Base(resolved), "", offset, nil
+		}
+		return "", spec, offset, nil
+	}
+	// resume device given directly as major:minor number
+	if dev, ok := resolveMajMin(src, spec); ok {
+		return dev, "", offset, nil
+	}
+	return "", spec, offset, nil
+}
+
+// isResumeDevice checks if active swap device is the one hibernation image is written to,
+// swap file is matched by block device of its filesystem when resume_offset is set
+func isResumeDevice(swap *swapCollector, device swapDevice, resumeDev, resumeSpec, offset string) bool {
+	if resumeDev == "" {
+		if device.header == nil {
+			return false
+		}
+		return (device.header.uuid != "" && resumeSpec == "UUID="+device.header.uuid) ||
+			(device.header.label != "" && resumeSpec == "LABEL="+device.header.label)
+	}
+	switch device.kind {
+	case "partition":
+		return blockDeviceName(swap.src, swap.src.devicePathInRoot(device.path)) == resumeDev
+	case "file":
+		blockDev := swap.devTags[device.name][tagBlockDevice]
+		return offset != "" && blockDev != "" && blockDeviceName(swap.src, swap.src.devicePathInRoot(blockDev)) == resumeDev
+	}
+	return false
+}
+
+// readCmdline returns parameters of kernel command line in src.cmdline,
+// parameters without value are mapped to empty string
+func readCmdline(src *sources) (map[string]string, error) {
+	params := map[string]string{}
+	data, err := readSource(src, src.cmdline)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return params, nil
+		}
+		return nil, sourceError(src.cmdline, err)
+	}
+	for _, param := range strings.Fields(string(data)) {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = kv[1]
+		} else {
+			params[kv[0]] = ""
+		}
+	}
+	return params, nil
+}
+
+// readImageSize returns preferred size of hibernation image, 0 if hibernation is not supported
+func readImageSize(src *sources) (float64, error) {
+	sizeS := readSysfsValue(src, src.powerImageSize)
+	if sizeS == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseFloat(sizeS, 64)
+	if err != nil {
+		return 0, parseError(src.powerImageSize, 0, "Hibernation image size", sizeS, err)
+	}
+	return size, nil
+}
+
+// readMemTotal returns total usable RAM listed in src.combined
+func readMemTotal(src *sources) (float64, error) {
+	buf, err := readSourceBuf(src, src.combined)
+	if err != nil {
+		return 0, sourceError(src.combined, err)
+	}
+	defer bufPool.Put(buf)
+	mem, err := swapstat.ParseMemInfo(*buf)
+	if err != nil {
+		return 0, statError(src.combined, err, nil)
+	}
+	return float64(mem.MemTotal), nil
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
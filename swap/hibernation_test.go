@@ -0,0 +1,106 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHibernationMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	src := defaultSources(fs)
+	createPriorityMockFiles(fs)
+	createHibernationMockFiles(fs, src)
+	swap := NewSwapCollectorFS(fs)
+	mts := []plugin.MetricType{}
+	for _, metric := range hibMetrics {
+		mts = append(mts, plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "hibernation", metric),
+		})
+	}
+	collect := func() map[string]float64 {
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 7)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace().Strings()[4]] = metric.Data().(float64)
+		}
+		return vals
+	}
+	Convey("resume device set in sysfs is active swap device", t, func() {
+		vals := collect()
+		So(vals["resume_configured"], ShouldEqual, 1)
+		So(vals["resume_device_active"], ShouldEqual, 1)
+		// sdb2 has 2000 kB of which 800 kB are used
+		So(vals["resume_free_bytes"], ShouldEqual, 1200*1024)
+		So(vals["image_size_bytes"], ShouldEqual, 1000000)
+		So(vals["image_headroom_bytes"], ShouldEqual, 1200*1024-1000000)
+		So(vals["mem_total_headroom_bytes"], ShouldEqual, (1200-400000)*1024)
+		So(vals["ready"], ShouldEqual, 1)
+	})
+	Convey("hibernation image does not fit into free space of resume device", t, func() {
+		writeMockValue(fs, src.powerImageSize, "2000000")
+		vals := collect()
+		So(vals["resume_device_active"], ShouldEqual, 1)
+		So(vals["image_headroom_bytes"], ShouldEqual, 1200*1024-2000000)
+		So(vals["ready"], ShouldEqual, 0)
+	})
+	Convey("resume device from kernel command line is not active", t, func() {
+		writeMockValue(fs, src.powerResume, "0:0")
+		writeMockValue(fs, src.cmdline, "root=/dev/sda1 resume=UUID=0a1b2c3d-0000-4000-8000-000000000009 quiet")
+		vals := collect()
+		So(vals["resume_configured"], ShouldEqual, 1)
+		So(vals["resume_device_active"], ShouldEqual, 0)
+		So(vals["resume_free_bytes"], ShouldEqual, 0)
+		So(vals["ready"], ShouldEqual, 0)
+	})
+	Convey("resume device from kernel command line is resolved by path", t, func() {
+		writeMockValue(fs, src.cmdline, "root=/dev/sda1 resume=/dev/sdb2")
+		writeMockValue(fs, src.powerImageSize, "0")
+		vals := collect()
+		So(vals["resume_device_active"], ShouldEqual, 1)
+		So(vals["ready"], ShouldEqual, 1)
+	})
+	Convey("resume device is not configured", t, func() {
+		delete(fs.Files, src.cmdline)
+		vals := collect()
+		So(vals["resume_configured"], ShouldEqual, 0)
+		So(vals["ready"], ShouldEqual, 0)
+	})
+}
+
+func createHibernationMockFiles(fs *MapFileSystem, src *sources) {
+	for _, dev := range []string{"sdb1", "sdb2", "sdc1", "sdc2", "sdd1"} {
+		fs.Files[filepath.Join(DevPathDir, dev)] = ""
+	}
+	fs.Dirs[filepath.Join(src.block, "sdb2")] = true
+	fs.Links[filepath.Join(src.devBlock, "8:18")] = "../../class/block/sdb2"
+	writeMockValue(fs, src.powerResume, "8:18")
+	writeMockValue(fs, src.powerImageSize, "1000000")
+	writeMockValue(fs, src.cmdline, "root=/dev/sda1 quiet")
+}
@@ -0,0 +1,169 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Modes of naming swap devices in namespace
+	deviceIDPath   = "path"
+	deviceIDUUID   = "uuid"
+	deviceIDLabel  = "label"
+	deviceIDByID   = "by-id"
+	deviceIDByPath = "by-path"
+
+	// Tag holding original path of swap device
+	tagDevicePath = "device_path"
+)
+
+var (
+	// Directories of persistent block device names data source holding symlinks used by device naming modes
+	deviceIDLinks = map[string]string{
+		deviceIDUUID:   "by-uuid",
+		deviceIDLabel:  "by-label",
+		deviceIDByID:   "by-id",
+		deviceIDByPath: "by-path",
+	}
+	deviceIDModes = []string{deviceIDPath, deviceIDUUID, deviceIDLabel, deviceIDByID, deviceIDByPath}
+)
+
+// deviceName returns namespace element identifying swap device with given path,
+// UUID and label are taken from swap header if device has no symlinks for them
+// and encoded path is used if device has no identifier of requested kind
+func deviceName(src *sources, path string, mode string, header *swapHeader) string {
+	if dir, ok := deviceIDLinks[mode]; ok {
+		if id, ok := findDiskLink(src, dir, path); ok {
+			return escapeElement(id, false)
+		}
+	}
+	if header != nil {
+		switch {
+		case mode == deviceIDUUID && header.uuid != "":
+			return escapeElement(header.uuid, false)
+		case mode == deviceIDLabel && header.label != "":
+			return escapeElement(header.label, false)
+		}
+	}
+	return encodeDevicePath(mapperPath(src, path))
+}
+
+// mapperPath returns path of device-mapper device with given path in /dev/mapper, e.g.
+// "/dev/mapper/vg0-swap" for "/dev/dm-3", as kernel names of device-mapper devices
+// depend on order of activation; other paths are returned unchanged
+func mapperPath(src *sources, path string) string {
+	if !pathHasPrefix(path, DevPathDir) {
+		return path
+	}
+	dev := blockDeviceName(src, src.devicePathInRoot(path))
+	if !strings.HasPrefix(dev, "dm-") {
+		return path
+	}
+	name := readSysfsValue(src, filepath.Join(src.block, dev, "dm", "name"))
+	if name == "" {
+		return path
+	}
+	return filepath.Join(DevPathDir, "mapper", name)
+}
+
+// devicePathInRoot returns path of swap device with given path, relocated to dev_path
+// if it is placed in /dev, e.g. when /dev of the host is mounted elsewhere in container
+func (src *sources) devicePathInRoot(path string) string {
+	if !pathHasPrefix(path, DevPathDir) {
+		return path
+	}
+	return filepath.Join(src.dev, strings.TrimPrefix(path, DevPathDir))
+}
+
+// findDiskLink returns name of symlink in src.disk subdirectory dir pointing to
+// device with given path, when several symlinks exist the first in order is used
+func findDiskLink(src *sources, dir string, path string) (string, bool) {
+	target, err := src.EvalSymlinks(src.devicePathInRoot(path))
+	if err != nil {
+		return "", false
+	}
+	links, err := src.ReadDir(filepath.Join(src.disk, dir))
+	if err != nil {
+		return "", false
+	}
+	for _, link := range links {
+		resolved, err := src.EvalSymlinks(filepath.Join(src.disk, dir, link.Name()))
+		if err == nil && resolved == target {
+			// udev escapes special characters of labels, e.g. "\x20" for space
+			return unescapeUdev(link.Name()), true
+		}
+	}
+	return "", false
+}
+
+// encodeDevicePath reversibly encodes path of swap device into namespace element,
+// slashes are replaced with underscores, e.g. "/dev/sda2" becomes "dev_sda2"
+func encodeDevicePath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, part := range parts {
+		parts[i] = escapeElement(part, true)
+	}
+	return strings.Join(parts, "_")
+}
+
+// escapeElement percent-encodes characters which are not allowed in namespace
+// elements, including slashes separating elements, underscores are encoded
+// as well if escapeUnderscore is set
+func escapeElement(s string, escapeUnderscore bool) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == ':', c == '+', c == '@', c == '=':
+			out = append(out, c)
+		case c == '_' && !escapeUnderscore:
+			out = append(out, c)
+		default:
+			out = append(out, []byte(fmt.Sprintf("%%%02X", c))...)
+		}
+	}
+	return string(out)
+}
+
+// unescapeUdev decodes "\xNN" sequences used by udev in names of symlinks
+func unescapeUdev(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				out = append(out, byte(c))
+				i += 3
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
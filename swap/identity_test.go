@@ -0,0 +1,168 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var devMockDir = "/host/dev"
+
+func TestDeviceIdentity(t *testing.T) {
+	fs := newMockFileSystem()
+	createDevMockFiles(fs)
+	mts := []plugin.MetricType{
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes"),
+		},
+	}
+	names := func(mode string) map[string]string {
+		swap := NewSwapCollectorFS(fs)
+		cfg := plugin.NewPluginConfigType()
+		cfg.AddItem(DevPathCfg, ctypes.ConfigValueStr{Value: devMockDir})
+		cfg.AddItem(DeviceIDCfg, ctypes.ConfigValueStr{Value: mode})
+		So(swap.setConfig(cfg), ShouldBeNil)
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 2)
+		names := map[string]string{}
+		for _, metric := range m {
+			names[metric.Tags()[tagDevicePath]] = metric.Namespace()[4].Value
+		}
+		return names
+	}
+	Convey("devices are named by path", t, func() {
+		n := names(deviceIDPath)
+		So(n["/dev/sda5"], ShouldEqual, "dev_sda5")
+		So(n["/dev/sda6"], ShouldEqual, "dev_sda6")
+	})
+	Convey("devices are named by UUID", t, func() {
+		n := names(deviceIDUUID)
+		So(n["/dev/sda5"], ShouldEqual, "0a3407de-014b-458b-b5c1-848e92a327a3")
+		// no UUID available, falls back to path
+		So(n["/dev/sda6"], ShouldEqual, "dev_sda6")
+	})
+	Convey("devices are named by LABEL", t, func() {
+		n := names(deviceIDLabel)
+		So(n["/dev/sda5"], ShouldEqual, "dev_sda5")
+		So(n["/dev/sda6"], ShouldEqual, "swap%20one")
+	})
+	Convey("devices are named by id", t, func() {
+		n := names(deviceIDByID)
+		So(n["/dev/sda5"], ShouldEqual, "ata-SAMSUNG_SSD_850-part5")
+	})
+	Convey("devices are named by path in device tree", t, func() {
+		n := names(deviceIDByPath)
+		So(n["/dev/sda5"], ShouldEqual, "pci-0000:00:1f%2E2-ata-1-part5")
+	})
+	Convey("slashes of identifiers are encoded", t, func() {
+		name := deviceName(defaultSources(fs), "/dev/sda7", deviceIDLabel, &swapHeader{label: "a/b"})
+		So(name, ShouldEqual, "a%2Fb")
+		stats := map[string]float64{name + "/used_bytes": 1}
+		m := dynamicMetrics(mts[0].Namespace(), stats, time.Now())
+		So(len(m), ShouldEqual, 1)
+		So(m[0].Namespace()[4].Value, ShouldEqual, "a%2Fb")
+	})
+	Convey("symlinks to devices are resolved in dev_path", t, func() {
+		src := newSources(fs, nil, ProcPathDir, SysPathDir, devMockDir, RootPathDir)
+		So(blockDeviceName(src, src.devicePathInRoot("/dev/disk/by-uuid/0a3407de-014b-458b-b5c1-848e92a327a3")), ShouldEqual, "sda5")
+	})
+	Convey("invalid naming mode", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		cfg := plugin.NewPluginConfigType()
+		cfg.AddItem(DeviceIDCfg, ctypes.ConfigValueStr{Value: "serial"})
+		err := swap.setConfig(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "is not a valid device_id")
+	})
+	Convey("device path encoding is reversible", t, func() {
+		for _, path := range []string{"/dev/sda2", "/var/swap_file.img", "/mnt/my disk/swap%1", "/dev/mapper/vg_0-swap"} {
+			name := encodeDevicePath(path)
+			So(name, ShouldNotContainSubstring, "/")
+			So(name, ShouldNotContainSubstring, ".")
+			So(name, ShouldNotContainSubstring, " ")
+			decoded, err := decodeDevicePath(name)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldEqual, path)
+		}
+		So(encodeDevicePath("/dev/mapper/vg_0-swap"), ShouldEqual, "dev_mapper_vg%5F0-swap")
+		_, err := decodeDevicePath("dev_sda%2")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// decodeDevicePath returns path of swap device encoded by encodeDevicePath, it is used to check
+// that encoding is reversible
+func decodeDevicePath(name string) (string, error) {
+	return unescapeElement("/" + strings.Replace(name, "_", "/", -1))
+}
+
+// unescapeElement decodes characters percent-encoded by escapeElement
+func unescapeElement(s string) (string, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			out = append(out, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("Invalid escape sequence in %s", s)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("Invalid escape sequence in %s", s)
+		}
+		out = append(out, byte(c))
+		i += 2
+	}
+	return string(out), nil
+}
+
+func createDevMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sda5 partition 55555 6666 -1\n" +
+		"/dev/sda6 partition 77777 8888 -2\n"
+	for _, dev := range []string{"sda", "sda5", "sda6"} {
+		fs.Files[filepath.Join(devMockDir, dev)] = ""
+	}
+	links := map[string]string{
+		"by-uuid/0a3407de-014b-458b-b5c1-848e92a327a3": "sda5",
+		"by-label/swap\\x20one":                        "sda6",
+		"by-id/ata-SAMSUNG_SSD_850":                    "sda",
+		"by-id/ata-SAMSUNG_SSD_850-part5":              "sda5",
+		"by-id/wwn-0x5002538d4099f4a2-part5":           "sda5",
+		"by-path/pci-0000:00:1f.2-ata-1-part5":         "sda5",
+	}
+	for link, dev := range links {
+		fs.Links[filepath.Join(devMockDir, "disk", link)] = filepath.Join("..", "..", dev)
+	}
+}
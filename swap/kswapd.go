@@ -0,0 +1,162 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Clock ticks per second used by procfs for process times (USER_HZ)
+	userHZ = 100.0
+)
+
+var (
+	// Per kswapd thread metrics
+	kswapdMetrics = []string{"cpu_percent", "user_percent", "system_percent"}
+)
+
+// kswapdData holds historic CPU times of kswapd thread for rate calculation
+type kswapdData struct {
+	utime     float64
+	stime     float64
+	timestamp time.Time
+}
+
+// getKswapdMetrics finds kswapdN kernel threads in src.proc and calculates
+// CPU usage of each node's threads since the previous collection; history is kept
+// per thread (pid), as a node might be served by several threads, e.g. "kswapd0:1"
+func getKswapdMetrics(swap *swapCollector) error {
+	entries, err := swap.src.ReadDir(swap.src.proc)
+	if err != nil {
+		return sourceError(swap.src.proc, err)
+	}
+	seen := map[string]bool{}
+	stats := map[string]float64{}
+	for _, entry := range entries {
+		pid := entry.Name()
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+		comm, err := readSource(swap.src, filepath.Join(swap.src.proc, pid, "comm"))
+		if err != nil {
+			// process might have exited in the meantime
+			continue
+		}
+		node, ok := kswapdNode(strings.TrimSpace(string(comm)))
+		if !ok {
+			continue
+		}
+		utime, stime, err := readProcessTimes(swap.src, filepath.Join(swap.src.proc, pid, "stat"))
+		if err != nil {
+			// thread might have exited since its comm was read, other threads are still reported
+			if e, ok := err.(*SourceUnavailableError); !ok || !e.NotExist() {
+				swap.logEntry().WithFields(log.Fields{"source": kswapdPrefix, "pid": pid}).Debug(err)
+				swap.src.countReadError()
+			}
+			continue
+		}
+		seen[pid] = true
+		// node is reported even if none of its threads has rate available yet
+		if _, ok := stats[node+"/"+kswapdMetrics[0]]; !ok {
+			for _, metric := range kswapdMetrics {
+				stats[node+"/"+metric] = 0
+			}
+		}
+		now := time.Now()
+		old, ok := swap.kswapdHistory[pid]
+		swap.kswapdHistory[pid] = kswapdData{utime: utime, stime: stime, timestamp: now}
+		duration := now.Sub(old.timestamp).Seconds()
+		// no rate is available for first observation or thread with reused pid
+		if !ok || utime < old.utime || stime < old.stime || duration <= 0 {
+			if ok && (utime < old.utime || stime < old.stime) {
+				swap.src.countReset()
+			}
+			continue
+		}
+		user := calcPercentage((utime-old.utime)/userHZ, duration)
+		system := calcPercentage((stime-old.stime)/userHZ, duration)
+		stats[node+"/"+kswapdMetrics[0]] += user + system
+		stats[node+"/"+kswapdMetrics[1]] += user
+		stats[node+"/"+kswapdMetrics[2]] += system
+	}
+	// forget threads which are gone, e.g. after node hot-remove
+	for pid := range swap.kswapdHistory {
+		if !seen[pid] {
+			delete(swap.kswapdHistory, pid)
+		}
+	}
+	swap.kswapdStats = stats
+	return nil
+}
+
+// kswapdNode returns node name served by kswapd thread with given comm
+func kswapdNode(comm string) (string, bool) {
+	if !strings.HasPrefix(comm, "kswapd") {
+		return "", false
+	}
+	n := strings.TrimPrefix(comm, "kswapd")
+	// comm might be suffixed with thread index, e.g. "kswapd0:1"
+	if i := strings.Index(n, ":"); i >= 0 {
+		n = n[:i]
+	}
+	if _, err := strconv.Atoi(n); err != nil {
+		return "", false
+	}
+	return "node" + n, true
+}
+
+// readProcessTimes returns utime and stime (in clock ticks) from /proc/[pid]/stat
+func readProcessTimes(fs FileSystem, path string) (float64, float64, error) {
+	content, err := readSource(fs, path)
+	if err != nil {
+		return 0, 0, sourceError(path, err)
+	}
+	// comm field is enclosed in parentheses and may contain spaces
+	stat := string(content)
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return 0, 0, parseError(path, 0, "comm", "", ErrMissingField)
+	}
+	fields := strings.Fields(stat[i+1:])
+	// utime and stime are 14th and 15th fields of stat, counted from state field (3rd) here
+	if len(fields) < 12 {
+		return 0, 0, parseError(path, 0, "utime", "", ErrMissingField)
+	}
+	if len(fields) < 13 {
+		return 0, 0, parseError(path, 0, "stime", "", ErrMissingField)
+	}
+	utime, err := strconv.ParseFloat(fields[11], 64)
+	if err != nil {
+		return 0, 0, parseError(path, 0, "utime", fields[11], err)
+	}
+	stime, err := strconv.ParseFloat(fields[12], 64)
+	if err != nil {
+		return 0, 0, parseError(path, 0, "stime", fields[12], err)
+	}
+	return utime, stime, nil
+}
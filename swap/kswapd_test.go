@@ -0,0 +1,152 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var procMockDir = ProcPathDir
+
+func TestKswapdMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createProcessMockFiles(fs, "1", "systemd", "10", "20")
+	createProcessMockFiles(fs, "45", "kswapd0", "100", "200")
+	createProcessMockFiles(fs, "46", "kswapd1", "0", "0")
+	swap := NewSwapCollectorFS(fs)
+	mts := []plugin.MetricType{
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "kswapd", "*", "cpu_percent"),
+		},
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "kswapd", "node0", "user_percent"),
+		},
+		plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "kswapd", "node0", "system_percent"),
+		},
+	}
+	Convey("first collection has no rate available", t, func() {
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		for _, metric := range m {
+			So(metric.Data(), ShouldEqual, 0)
+		}
+	})
+	Convey("CPU usage is calculated between collections", t, func() {
+		// 1s of user time and 0.5s of system time during 2s
+		createProcessMockFiles(fs, "45", "kswapd0", "200", "250")
+		h := swap.kswapdHistory["45"]
+		h.timestamp = time.Now().Add(-2 * time.Second)
+		swap.kswapdHistory["45"] = h
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[4]+"/"+ns[5]] = metric.Data().(float64)
+		}
+		So(vals["node0/user_percent"], ShouldAlmostEqual, 50, 0.1)
+		So(vals["node0/system_percent"], ShouldAlmostEqual, 25, 0.1)
+		So(vals["node0/cpu_percent"], ShouldAlmostEqual, 75, 0.1)
+		So(vals["node1/cpu_percent"], ShouldEqual, 0)
+	})
+	Convey("CPU usage of threads serving the same node is summed", t, func() {
+		createProcessMockFiles(fs, "47", "kswapd0:1", "0", "0")
+		_, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// 0.5s of user time of each thread during 1s
+		createProcessMockFiles(fs, "45", "kswapd0", "250", "250")
+		createProcessMockFiles(fs, "47", "kswapd0:1", "50", "0")
+		for _, pid := range []string{"45", "47"} {
+			h := swap.kswapdHistory[pid]
+			h.timestamp = time.Now().Add(-time.Second)
+			swap.kswapdHistory[pid] = h
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[4]+"/"+ns[5]] = metric.Data().(float64)
+		}
+		So(vals["node0/user_percent"], ShouldAlmostEqual, 100, 0.1)
+		So(vals["node0/system_percent"], ShouldAlmostEqual, 0, 0.1)
+		So(vals["node0/cpu_percent"], ShouldAlmostEqual, 100, 0.1)
+		deleteProcessMockFiles(fs, "47")
+	})
+	Convey("thread which is gone is not reported", t, func() {
+		deleteProcessMockFiles(fs, "46")
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		So(m[0].Namespace()[4].Value, ShouldEqual, "node0")
+	})
+	Convey("thread which exits while being read is skipped", t, func() {
+		createProcessMockFiles(fs, "46", "kswapd1", "0", "0")
+		delete(fs.Files, filepath.Join(procMockDir, "45", "stat"))
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		So(m[0].Namespace()[4].Value, ShouldEqual, "node1")
+		So(swap.errStats["kswapd/read_errors"], ShouldEqual, 0)
+	})
+	Convey("stat file with errors is skipped and counted", t, func() {
+		createProcessMockFiles(fs, "45", "kswapd0", "not-an-int", "250")
+		m, err := swap.CollectMetrics(mts[:1])
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		So(m[0].Namespace()[4].Value, ShouldEqual, "node1")
+		So(swap.errStats["kswapd/read_errors"], ShouldEqual, 1)
+		So(swap.available[kswapdPrefix], ShouldBeTrue)
+	})
+	Convey("helper routines", t, func() {
+		node, ok := kswapdNode("kswapd12")
+		So(ok, ShouldBeTrue)
+		So(node, ShouldEqual, "node12")
+		node, ok = kswapdNode("kswapd0:1")
+		So(ok, ShouldBeTrue)
+		So(node, ShouldEqual, "node0")
+		_, ok = kswapdNode("kswapd_helper")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func createProcessMockFiles(fs *MapFileSystem, pid, comm, utime, stime string) {
+	dir := filepath.Join(procMockDir, pid)
+	fs.Files[filepath.Join(dir, "comm")] = comm + "\n"
+	fs.Files[filepath.Join(dir, "stat")] = fmt.Sprintf("%s (%s) S 2 0 0 0 -1 2129984 0 0 0 0 %s %s 0 0 20 0 1 0 80 0 0 "+
+		"18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 17 0 0 0 0 0 0\n", pid, comm, utime, stime)
+}
+
+func deleteProcessMockFiles(fs *MapFileSystem, pid string) {
+	delete(fs.Files, filepath.Join(procMockDir, pid, "comm"))
+	delete(fs.Files, filepath.Join(procMockDir, pid, "stat"))
+}
@@ -0,0 +1,60 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap-plugin-utilities/config"
+	"github.com/intelsdi-x/snap/control/plugin"
+	log "github.com/sirupsen/logrus"
+)
+
+// logEntry returns entry of plugin logger messages of collector are logged with,
+// working copy of collector logs with name of task of its collection, see fork
+func (swap *swapCollector) logEntry() *log.Entry {
+	return swap.entry
+}
+
+// taskEntry returns entry of plugin logger for collection of metrics mts, which is named
+// by task_name of their configuration; tasks sharing plugin instance are told apart by it
+func (swap *swapCollector) taskEntry(mts []plugin.MetricType) *log.Entry {
+	taskName, err := config.GetConfigItem(mts[0], TaskNameCfg)
+	if err != nil || len(taskName.(string)) == 0 {
+		return swap.logEntry()
+	}
+	return swap.logEntry().WithFields(log.Fields{"task": taskName.(string)})
+}
+
+// setLogLevel sets level of plugin logger, e.g. "debug" or "warning"
+func (swap *swapCollector) setLogLevel(level string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid %s: %s", level, LogLevelCfg, err)
+	}
+	if lvl != swap.logger.Level {
+		old := swap.logger.Level
+		swap.logger.SetLevel(lvl)
+		swap.logEntry().WithFields(log.Fields{"old": old.String(), "new": lvl.String()}).Info("log_level changed")
+	}
+	return nil
+}
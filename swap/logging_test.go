@@ -0,0 +1,136 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	log "github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogging(t *testing.T) {
+	fs := newMockFileSystem()
+	Convey("log level and task name are configurable", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		out := &bytes.Buffer{}
+		swap.logger.Out = out
+		swap.logger.Formatter = &log.JSONFormatter{}
+		node := cdata.NewNode()
+		node.AddItem(LogLevelCfg, ctypes.ConfigValueStr{Value: "debug"})
+		node.AddItem(TaskNameCfg, ctypes.ConfigValueStr{Value: "swap-dashboard"})
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "used_bytes"),
+				Config_:    node,
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		So(swap.logger.Level, ShouldEqual, log.DebugLevel)
+		entries := []map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			entry := map[string]interface{}{}
+			So(json.Unmarshal([]byte(line), &entry), ShouldBeNil)
+			entries = append(entries, entry)
+		}
+		last := entries[len(entries)-1]
+		So(last["msg"], ShouldEqual, "Metrics collected")
+		So(last["task"], ShouldEqual, "swap-dashboard")
+		So(last["plugin"], ShouldEqual, PluginName)
+		So(last["collected"], ShouldEqual, 1)
+	})
+	Convey("tasks sharing plugin instance log with their own names", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		out := &bytes.Buffer{}
+		swap.logger.Out = out
+		swap.logger.Formatter = &log.JSONFormatter{}
+		for _, task := range []string{"swap-dashboard", "swap-alerts", "swap-dashboard"} {
+			node := cdata.NewNode()
+			node.AddItem(TaskNameCfg, ctypes.ConfigValueStr{Value: task})
+			mts := []plugin.MetricType{
+				plugin.MetricType{
+					Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "used_bytes"),
+					Config_:    node,
+				},
+				// missing metric is logged as warning
+				plugin.MetricType{
+					Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "dummy", "free_bytes"),
+					Config_:    node,
+				},
+			}
+			_, err := swap.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+		}
+		tasks := []interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			entry := map[string]interface{}{}
+			So(json.Unmarshal([]byte(line), &entry), ShouldBeNil)
+			if entry["source"] == devPrefix {
+				tasks = append(tasks, entry["task"])
+			}
+		}
+		So(tasks, ShouldResemble, []interface{}{"swap-dashboard", "swap-alerts", "swap-dashboard"})
+	})
+	Convey("debug messages are not logged by default", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		out := &bytes.Buffer{}
+		swap.logger.Out = out
+		_, err := swap.CollectMetrics(mockMts[8:])
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldNotContainSubstring, "Metrics collected")
+	})
+	Convey("log level of running plugin can be changed", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		out := &bytes.Buffer{}
+		swap.logger.Out = out
+		mts := mockMts[8:9]
+		_, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldNotContainSubstring, "Metrics collected")
+		node := cdata.NewNode()
+		node.AddItem(LogLevelCfg, ctypes.ConfigValueStr{Value: "debug"})
+		mts[0].Config_ = node
+		_, err = swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(swap.logger.Level, ShouldEqual, log.DebugLevel)
+		So(out.String(), ShouldContainSubstring, "log_level changed")
+		So(out.String(), ShouldContainSubstring, "Metrics collected")
+		mts[0].Config_ = nil
+	})
+	Convey("invalid log level", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		cfg := plugin.NewPluginConfigType()
+		cfg.AddItem(LogLevelCfg, ctypes.ConfigValueStr{Value: "verbose"})
+		err := swap.setConfig(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "is not a valid log_level")
+	})
+}
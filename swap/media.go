@@ -0,0 +1,167 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Tags describing storage media of swap device
+	tagMedia            = "media"
+	tagRotational       = "rotational"
+	tagTransport        = "transport"
+	tagDiscard          = "discard"
+	tagLogicalBlockSize = "logical_block_size"
+	tagDisks            = "disks"
+
+	// Maximum depth of stacked block devices, e.g. partition on LVM on LUKS on RAID
+	maxStackDepth = 8
+)
+
+var (
+	// Transports recognized in sysfs device path, checked in order
+	transportPaths = []struct{ pattern, transport string }{
+		{"/nvme", "nvme"},
+		{"/virtio", "virtio"},
+		{"/usb", "usb"},
+		{"/ata", "sata"},
+		{"/mmc", "mmc"},
+		{"/vbd-", "xen"},
+		{"/host", "scsi"},
+	}
+	// Transports recognized by kernel name of the disk, checked in order
+	transportNames = []struct{ prefix, transport string }{
+		{"nvme", "nvme"},
+		{"vd", "virtio"},
+		{"xvd", "xen"},
+		{"mmcblk", "mmc"},
+		{"zram", "zram"},
+		{"ram", "ram"},
+		{"loop", "loop"},
+		{"sd", "scsi"},
+	}
+)
+
+// addMediaTags tags swap device backed by block device dev with characteristics of
+// storage media read from src.block, going through device-mapper and partitions
+// down to physical disks
+func addMediaTags(src *sources, tags map[string]string, dev string) {
+	disks := physicalDisks(src, dev, 0)
+	if len(disks) == 0 {
+		return
+	}
+	rotational := false
+	transport := ""
+	for _, disk := range disks {
+		if readSysfsValue(src, filepath.Join(src.block, disk, "queue", "rotational")) == "1" {
+			rotational = true
+		}
+		t := diskTransport(src, disk)
+		if transport == "" {
+			transport = t
+		} else if transport != t {
+			transport = "mixed"
+		}
+	}
+	media := "ssd"
+	switch {
+	case transport == "zram" || transport == "ram":
+		media = "ram"
+	case rotational:
+		media = "hdd"
+	}
+	tags[tagDisks] = strings.Join(disks, ",")
+	tags[tagMedia] = media
+	tags[tagRotational] = boolTag(rotational)
+	tags[tagTransport] = transport
+	// discard and block size are reported by the top device queue, partitions do not have one
+	queueDev := dev
+	if _, err := src.Stat(filepath.Join(src.block, dev, "queue")); err != nil {
+		queueDev = disks[0]
+	}
+	if discard := readSysfsValue(src, filepath.Join(src.block, queueDev, "queue", "discard_max_bytes")); discard != "" {
+		tags[tagDiscard] = boolTag(discard != "0")
+	}
+	if size := readSysfsValue(src, filepath.Join(src.block, queueDev, "queue", "logical_block_size")); size != "" {
+		tags[tagLogicalBlockSize] = size
+	}
+}
+
+// physicalDisks returns names of whole disks holding block device dev, partitions
+// are resolved to their parent disk and stacked devices (e.g. device-mapper) to their slaves
+func physicalDisks(src *sources, dev string, depth int) []string {
+	dir := filepath.Join(src.block, dev)
+	if _, err := src.Stat(dir); err != nil || depth > maxStackDepth {
+		return nil
+	}
+	slaves, err := src.ReadDir(filepath.Join(dir, "slaves"))
+	if err == nil && len(slaves) > 0 {
+		disks := []string{}
+		for _, slave := range slaves {
+			disks = append(disks, physicalDisks(src, slave.Name(), depth+1)...)
+		}
+		return disks
+	}
+	if _, err := src.Stat(filepath.Join(dir, "partition")); err == nil {
+		// partition directory is placed in directory of its parent disk
+		if link, err := src.EvalSymlinks(dir); err == nil {
+			return physicalDisks(src, filepath.Base(filepath.Dir(link)), depth+1)
+		}
+	}
+	return []string{dev}
+}
+
+// diskTransport returns transport of disk, recognized by its sysfs device path
+// or, if it is not conclusive, by its kernel name
+func diskTransport(src *sources, disk string) string {
+	if link, err := src.EvalSymlinks(filepath.Join(src.block, disk)); err == nil {
+		for _, t := range transportPaths {
+			if strings.Contains(link, t.pattern) {
+				return t.transport
+			}
+		}
+	}
+	for _, t := range transportNames {
+		if strings.HasPrefix(disk, t.prefix) {
+			return t.transport
+		}
+	}
+	return "unknown"
+}
+
+// readSysfsValue returns content of sysfs attribute file, empty if not available
+func readSysfsValue(fs FileSystem, path string) string {
+	content, err := readSource(fs, path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func boolTag(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,107 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var sysBlockMockDir = SysPathDir
+
+func TestMediaTags(t *testing.T) {
+	fs := newMockFileSystem()
+	createMediaMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	Convey("swap devices are tagged with storage media", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 3)
+		tags := map[string]map[string]string{}
+		for _, metric := range m {
+			tags[metric.Namespace()[4].Value] = metric.Tags()
+		}
+		So(tags["dev_sda5"][tagDisks], ShouldEqual, "sda")
+		So(tags["dev_sda5"][tagMedia], ShouldEqual, "hdd")
+		So(tags["dev_sda5"][tagRotational], ShouldEqual, "true")
+		So(tags["dev_sda5"][tagTransport], ShouldEqual, "sata")
+		So(tags["dev_sda5"][tagDiscard], ShouldEqual, "false")
+		So(tags["dev_sda5"][tagLogicalBlockSize], ShouldEqual, "512")
+		// device-mapper device on NVMe partition
+		So(tags["dev_dm-0"][tagDisks], ShouldEqual, "nvme0n1")
+		So(tags["dev_dm-0"][tagMedia], ShouldEqual, "ssd")
+		So(tags["dev_dm-0"][tagRotational], ShouldEqual, "false")
+		So(tags["dev_dm-0"][tagTransport], ShouldEqual, "nvme")
+		So(tags["dev_dm-0"][tagDiscard], ShouldEqual, "true")
+		So(tags["dev_dm-0"][tagLogicalBlockSize], ShouldEqual, "4096")
+		So(tags["dev_zram0"][tagMedia], ShouldEqual, "ram")
+		So(tags["dev_zram0"][tagTransport], ShouldEqual, "zram")
+	})
+	Convey("helper routines", t, func() {
+		So(physicalDisks(swap.src, "sdx", 0), ShouldBeEmpty)
+		So(diskTransport(swap.src, "vdb"), ShouldEqual, "virtio")
+		So(diskTransport(swap.src, "foo"), ShouldEqual, "unknown")
+	})
+}
+
+func createMediaMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sda5 partition 55555 6666 -1\n" +
+		"/dev/dm-0 partition 77777 8888 -2\n" +
+		"/dev/zram0 partition 99999 0 100\n"
+	devices := filepath.Join(sysBlockMockDir, "devices")
+	classBlock := filepath.Join(sysBlockMockDir, "class", "block")
+	disks := map[string]struct {
+		path, part, rotational, discard, blockSize string
+	}{
+		"sda":     {"pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda", "sda5", "1", "0", "512"},
+		"nvme0n1": {"pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0/nvme0n1", "nvme0n1p2", "0", "2199023255040", "512"},
+		"dm-0":    {"virtual/block/dm-0", "", "0", "2199023255040", "4096"},
+		"zram0":   {"virtual/block/zram0", "", "0", "4096", "4096"},
+	}
+	for name, disk := range disks {
+		dir := filepath.Join(devices, disk.path)
+		writeMockValue(fs, filepath.Join(dir, "queue", "rotational"), disk.rotational)
+		writeMockValue(fs, filepath.Join(dir, "queue", "discard_max_bytes"), disk.discard)
+		writeMockValue(fs, filepath.Join(dir, "queue", "logical_block_size"), disk.blockSize)
+		fs.Links[filepath.Join(classBlock, name)] = dir
+		if disk.part != "" {
+			writeMockValue(fs, filepath.Join(dir, disk.part, "partition"), "1")
+			fs.Links[filepath.Join(classBlock, disk.part)] = filepath.Join(dir, disk.part)
+		}
+	}
+	fs.Links[filepath.Join(devices, disks["dm-0"].path, "slaves", "nvme0n1p2")] =
+		filepath.Join(devices, disks["nvme0n1"].path, "nvme0n1p2")
+}
+
+func writeMockValue(fs *MapFileSystem, path, value string) {
+	fs.Files[path] = value + "\n"
+}
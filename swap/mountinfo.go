@@ -0,0 +1,115 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+const (
+	// Tags describing filesystem which holds swap file
+	tagMountPoint  = "mount_point"
+	tagFsType      = "fs_type"
+	tagBlockDevice = "block_device"
+)
+
+// mountPoint holds single entry of mountinfo
+type mountPoint struct {
+	path   string
+	majMin string
+	fsType string
+	source string
+}
+
+// readMountinfo parses src.mountinfo, where every line has format:
+// ID PARENT MAJ:MIN ROOT MOUNT_POINT OPTIONS [OPTIONAL_FIELDS...] - FSTYPE SOURCE SUPER_OPTIONS
+func readMountinfo(src *sources) ([]mountPoint, error) {
+	fd, err := openSource(src, src.mountinfo)
+	if err != nil {
+		return nil, sourceError(src.mountinfo, err)
+	}
+	defer fd.Close()
+	mounts := []mountPoint{}
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || len(fields) < sep+3 {
+			if len(fields) > 0 {
+				src.skipLine()
+			}
+			continue
+		}
+		mounts = append(mounts, mountPoint{
+			path:   swapstat.UnescapeOctal(fields[4]),
+			majMin: fields[2],
+			fsType: fields[sep+1],
+			source: swapstat.UnescapeOctal(fields[sep+2]),
+		})
+	}
+	return mounts, nil
+}
+
+// findMountPoint returns mount point holding given path, the most specific one wins
+// and of mounts stacked on the same path the last one is visible
+func findMountPoint(mounts []mountPoint, path string) (mountPoint, bool) {
+	found := -1
+	for i, mount := range mounts {
+		if !pathHasPrefix(path, mount.path) {
+			continue
+		}
+		if found < 0 || len(mount.path) >= len(mounts[found].path) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return mountPoint{}, false
+	}
+	return mounts[found], true
+}
+
+func pathHasPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return strings.HasPrefix(path, "/")
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// resolveMajMin returns kernel name of block device with given major:minor number
+func resolveMajMin(src *sources, majMin string) (string, bool) {
+	link, err := src.EvalSymlinks(filepath.Join(src.devBlock, majMin))
+	if err != nil {
+		return "", false
+	}
+	return filepath.Base(link), true
+}
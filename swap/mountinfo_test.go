@@ -0,0 +1,97 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	mountinfoMockFile = ProcPathDir + "/self/mountinfo"
+	sysDevMockDir     = SysPathDir
+)
+
+func TestDevTags(t *testing.T) {
+	fs := newMockFileSystem()
+	createSwapFilesMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	Convey("swap files are resolved to backing filesystem", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "used_bytes"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		tags := map[string]map[string]string{}
+		for _, metric := range m {
+			tags[metric.Namespace()[4].Value] = metric.Tags()
+		}
+		So(tags["dev_sda5"], ShouldResemble, map[string]string{tagDevicePath: "/dev/sda5"})
+		So(tags["swapfile"][tagMountPoint], ShouldEqual, "/")
+		So(tags["swapfile"][tagFsType], ShouldEqual, "ext4")
+		So(tags["swapfile"][tagBlockDevice], ShouldEqual, "/dev/sda2")
+		So(tags["var_swap%2Eimg"][tagMountPoint], ShouldEqual, "/var")
+		So(tags["var_swap%2Eimg"][tagFsType], ShouldEqual, "xfs")
+		So(tags["var_swap%2Eimg"][tagBlockDevice], ShouldEqual, "/dev/sdb1")
+		So(tags["mnt_my%20disk_swap"][tagMountPoint], ShouldEqual, "/mnt/my disk")
+		So(tags["mnt_my%20disk_swap"][tagFsType], ShouldEqual, "btrfs")
+		So(tags["mnt_my%20disk_swap"][tagBlockDevice], ShouldEqual, "/dev/sdc")
+	})
+	Convey("mountinfo not available", t, func() {
+		delete(fs.Files, mountinfoMockFile)
+		m, err := swap.CollectMetrics(mockMts[4:5])
+		// metrics are still reported, failure is counted
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		So(swap.errStats["device_tags/read_errors"], ShouldEqual, 1)
+	})
+	Convey("helper routines", t, func() {
+		So(pathHasPrefix("/var/swap", "/var"), ShouldBeTrue)
+		So(pathHasPrefix("/varx/swap", "/var"), ShouldBeFalse)
+		So(pathHasPrefix("/swapfile", "/"), ShouldBeTrue)
+	})
+}
+
+func createSwapFilesMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sda5 partition 55555 6666 -1\n" +
+		"/swapfile file 1048572 0 -2\n" +
+		"/var/swap.img file 1048572 0 -3\n" +
+		"/mnt/my\\040disk/swap file 1048572 0 -4\n"
+	fs.Files[mountinfoMockFile] = "22 28 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw\n" +
+		"28 1 8:2 / / rw,relatime shared:1 - ext4 /dev/sda2 rw,errors=remount-ro\n" +
+		"30 28 8:17 / /var rw,relatime shared:2 - ext4 /dev/sdb1 rw\n" +
+		"31 28 8:17 / /var rw,relatime shared:3 - xfs /dev/sdb1 rw,attr2\n" +
+		"32 28 0:45 / /mnt/my\\040disk rw,relatime shared:4 master:1 - btrfs /dev/sdc rw,space_cache\n" +
+		"bad-entry\n"
+	for majMin, dev := range map[string]string{"8:2": "sda2", "8:17": "sdb1"} {
+		fs.Dirs[filepath.Join(sysDevMockDir, "devices", "block", dev)] = true
+		fs.Links[filepath.Join(sysDevMockDir, "dev", "block", majMin)] = filepath.Join("..", "..", "devices", "block", dev)
+	}
+}
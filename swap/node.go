@@ -0,0 +1,160 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Per NUMA node metrics
+	nodeMetrics = []string{"free_bytes", "anon_active_bytes", "anon_inactive_bytes", "file_active_bytes", "file_inactive_bytes",
+		"pgscan", "pgsteal", "workingset_refault", "workingset_activate", "workingset_restore"}
+	// Node meminfo fields (in kB) mapped to node metrics
+	nodeMemFields = map[string]string{
+		"MemFree:":        nodeMetrics[0],
+		"Active(anon):":   nodeMetrics[1],
+		"Inactive(anon):": nodeMetrics[2],
+		"Active(file):":   nodeMetrics[3],
+		"Inactive(file):": nodeMetrics[4],
+	}
+	// Node vmstat counters summed up into node metrics
+	nodeVMStatFields = map[string]string{
+		"pgscan_kswapd":            nodeMetrics[5],
+		"pgscan_direct":            nodeMetrics[5],
+		"pgscan_khugepaged":        nodeMetrics[5],
+		"pgsteal_kswapd":           nodeMetrics[6],
+		"pgsteal_direct":           nodeMetrics[6],
+		"pgsteal_khugepaged":       nodeMetrics[6],
+		"workingset_refault":       nodeMetrics[7],
+		"workingset_refault_anon":  nodeMetrics[7],
+		"workingset_refault_file":  nodeMetrics[7],
+		"workingset_activate":      nodeMetrics[8],
+		"workingset_activate_anon": nodeMetrics[8],
+		"workingset_activate_file": nodeMetrics[8],
+		"workingset_restore":       nodeMetrics[9],
+		"workingset_restore_anon":  nodeMetrics[9],
+		"workingset_restore_file":  nodeMetrics[9],
+	}
+)
+
+// getNodeMetrics gathers metrics of every NUMA node found in src.node,
+// nothing is gathered if kernel does not expose NUMA topology
+func getNodeMetrics(src *sources, dest map[string]float64) error {
+	entries, err := src.ReadDir(src.node)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return sourceError(src.node, err)
+	}
+	for _, entry := range entries {
+		node := entry.Name()
+		if !strings.HasPrefix(node, "node") {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(node, "node")); err != nil {
+			continue
+		}
+		err := getNodeMemInfo(src, node, dest)
+		if err != nil {
+			return err
+		}
+		err = getNodeVMStat(src, node, dest)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getNodeMemInfo parses nodeN/meminfo, where lines have format "Node N Field: value kB"
+func getNodeMemInfo(src *sources, node string, dest map[string]float64) error {
+	path := filepath.Join(src.node, node, "meminfo")
+	fd, err := openSource(src, path)
+	if err != nil {
+		return sourceError(path, err)
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		metric, ok := nodeMemFields[fields[2]]
+		if !ok {
+			continue
+		}
+		val, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return parseError(path, lineNo, strings.TrimSuffix(fields[2], ":")+" of "+node, fields[3], err)
+		}
+		dest[node+"/"+metric] = val * 1024.0
+	}
+	return nil
+}
+
+// getNodeVMStat parses nodeN/vmstat and sums up reclaim and workingset counters,
+// file is skipped if missing
+func getNodeVMStat(src *sources, node string, dest map[string]float64) error {
+	path := filepath.Join(src.node, node, "vmstat")
+	fd, err := openSource(src, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return sourceError(path, err)
+	}
+	defer fd.Close()
+	counters := map[string]float64{}
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		metric, ok := nodeVMStatFields[fields[0]]
+		if !ok {
+			continue
+		}
+		val, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return parseError(path, lineNo, fields[0]+" of "+node, fields[1], err)
+		}
+		counters[metric] += val
+	}
+	for _, metric := range nodeMetrics[5:] {
+		dest[node+"/"+metric] = counters[metric]
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var nodeMockDir = SysPathDir + "/devices/system/node"
+
+func TestNodeMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createNodeMockFiles(fs, "1000")
+	swap := NewSwapCollectorFS(fs)
+	Convey("per node metrics", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "*", "free_bytes"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "node1", "pgscan"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "node1", "workingset_refault"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// 2 nodes for wildcard, 1 for each specific metric
+		So(len(m), ShouldEqual, 4)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace()[4].Value+"/"+metric.Namespace()[5].Value] = metric.Data().(float64)
+		}
+		So(vals["node0/free_bytes"], ShouldEqual, 1000*1024.0)
+		So(vals["node1/free_bytes"], ShouldEqual, 1000*1024.0)
+		// pgscan_kswapd + pgscan_direct, pgscan_direct_throttle is not summed up
+		So(vals["node1/pgscan"], ShouldEqual, 30)
+		// workingset_refault_anon + workingset_refault_file
+		So(vals["node1/workingset_refault"], ShouldEqual, 12)
+	})
+	Convey("requested node does not exist", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "node7", "pgscan"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Requested per node swap stat")
+		So(len(m), ShouldEqual, 0)
+	})
+	Convey("node meminfo with errors", t, func() {
+		createNodeMockFiles(fs, "not-an-int")
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics(mockMts[:1])
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		m, err = swap.CollectMetrics([]plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "*", "free_bytes"),
+			},
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "MemFree of node0 is not a number")
+		So(m, ShouldBeEmpty)
+	})
+	Convey("NUMA topology not exposed", t, func() {
+		deleteNodeMockFiles(fs)
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics([]plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "node", "*", "free_bytes"),
+			},
+		})
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 0)
+	})
+}
+
+func createNodeMockFiles(fs *MapFileSystem, memFree string) {
+	for _, node := range []string{"node0", "node1"} {
+		dir := filepath.Join(nodeMockDir, node)
+		fs.Files[filepath.Join(dir, "meminfo")] = "Node " + node[4:] + " MemTotal: 4000 kB\n" +
+			"Node " + node[4:] + " MemFree: " + memFree + " kB\n" +
+			"Node " + node[4:] + " Active(anon): 100 kB\n" +
+			"Node " + node[4:] + " Inactive(anon): 200 kB\n" +
+			"Node " + node[4:] + " Active(file): 300 kB\n" +
+			"Node " + node[4:] + " Inactive(file): 400 kB\n"
+		fs.Files[filepath.Join(dir, "vmstat")] = "nr_free_pages 250\npgscan_kswapd 20\npgscan_direct 10\npgscan_direct_throttle 5\n" +
+			"pgsteal_kswapd 15\npgsteal_direct 5\nworkingset_refault_anon 4\nworkingset_refault_file 8\n" +
+			"workingset_activate_anon 1\nworkingset_activate_file 2\nworkingset_restore_anon 3\nworkingset_restore_file 3\n"
+	}
+	fs.Dirs[filepath.Join(nodeMockDir, "power")] = true
+}
+
+func deleteNodeMockFiles(fs *MapFileSystem) {
+	for _, node := range []string{"node0", "node1"} {
+		delete(fs.Files, filepath.Join(nodeMockDir, node, "meminfo"))
+		delete(fs.Files, filepath.Join(nodeMockDir, node, "vmstat"))
+	}
+	delete(fs.Dirs, filepath.Join(nodeMockDir, "power"))
+}
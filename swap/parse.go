@@ -0,0 +1,75 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"sync"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
+)
+
+// Buffers data source files are read into, shared by collections
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 16*1024)
+		return &buf
+	},
+}
+
+// Parsers of swap devices list, shared by collections
+var swapsPool = sync.Pool{
+	New: func() interface{} {
+		return &swapstat.SwapsParser{}
+	},
+}
+
+// readSourceBuf reads whole data source file into buffer taken from bufPool,
+// which should be put back once content is parsed
+func readSourceBuf(fs FileSystem, path string) (*[]byte, error) {
+	fd, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	buf := bufPool.Get().(*[]byte)
+	content, err := swapstat.ReadAll(fd, (*buf)[:0])
+	*buf = content
+	if err != nil {
+		bufPool.Put(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// statError converts error of swapstat parser of data source file to error of the plugin,
+// name returns device name of swap area path used in messages
+func statError(file string, err error, name func(path string) string) error {
+	parseErr, ok := err.(*swapstat.ParseError)
+	if !ok {
+		return err
+	}
+	field := parseErr.Field
+	if parseErr.Device != "" {
+		field += " for " + name(parseErr.Device)
+	}
+	return parseError(file, parseErr.Line, field, parseErr.Value, parseErr.Err)
+}
@@ -0,0 +1,93 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"math"
+	"strconv"
+)
+
+var (
+	// Per swap priority group metrics
+	prioMetrics = []string{"device_count", "used_percent_cv"}
+	// Per device swap priority metrics
+	devPrioMetrics = []string{"priority", "starved"}
+)
+
+// getPriorityMetrics analyses balance of swap usage between devices found by getDevMetrics,
+// which share the same priority and so should be used in round-robin manner by kernel
+func getPriorityMetrics(swap *swapCollector) {
+	groups := map[string][]swapDevice{}
+	for _, device := range swap.devices {
+		groups[device.priority] = append(groups[device.priority], device)
+	}
+	prioStats := map[string]float64{}
+	devPrioStats := map[string]float64{}
+	for priority, devices := range groups {
+		usedPercent := make([]float64, len(devices))
+		anyUsed := false
+		for i, device := range devices {
+			usedPercent[i] = calcPercentage(device.used, device.size)
+			if device.used > 0 {
+				anyUsed = true
+			}
+		}
+		prioStats[priority+"/"+prioMetrics[0]] = float64(len(devices))
+		prioStats[priority+"/"+prioMetrics[1]] = coefficientOfVariation(usedPercent)
+		for _, device := range devices {
+			prio, err := strconv.ParseFloat(device.priority, 64)
+			if err == nil {
+				devPrioStats[device.name+"/"+devPrioMetrics[0]] = prio
+			}
+			// device is starved if it receives no pages while others of its group fill up
+			starved := 0.0
+			if device.used == 0 && anyUsed {
+				starved = 1
+			}
+			devPrioStats[device.name+"/"+devPrioMetrics[1]] = starved
+		}
+	}
+	swap.prioStats = prioStats
+	swap.devPrioStats = devPrioStats
+}
+
+// coefficientOfVariation returns ratio of population standard deviation to mean of values,
+// 0 is returned if mean is 0
+func coefficientOfVariation(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+	return math.Sqrt(variance) / mean
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPriorityMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createPriorityMockFiles(fs)
+	swap := NewSwapCollectorFS(fs)
+	Convey("swap usage balance within priority groups", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "priority", "*", "device_count"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "priority", "*", "used_percent_cv"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "*", "starved"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "device", "dev_sdc1", "priority"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// 3 priority groups, 5 devices
+		So(len(m), ShouldEqual, 12)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[3]+"/"+ns[4]+"/"+ns[5]] = metric.Data().(float64)
+		}
+		So(vals["priority/10/device_count"], ShouldEqual, 2)
+		So(vals["priority/10/used_percent_cv"], ShouldEqual, 0)
+		So(vals["priority/5/device_count"], ShouldEqual, 2)
+		// used percents 50 and 0
+		So(vals["priority/5/used_percent_cv"], ShouldEqual, 1)
+		So(vals["priority/-2/device_count"], ShouldEqual, 1)
+		So(vals["device/dev_sdb1/starved"], ShouldEqual, 0)
+		So(vals["device/dev_sdb2/starved"], ShouldEqual, 0)
+		So(vals["device/dev_sdc1/starved"], ShouldEqual, 0)
+		So(vals["device/dev_sdc2/starved"], ShouldEqual, 1)
+		So(vals["device/dev_sdc1/priority"], ShouldEqual, 5)
+	})
+	Convey("coefficient of variation", t, func() {
+		So(coefficientOfVariation(nil), ShouldEqual, 0)
+		So(coefficientOfVariation([]float64{0, 0}), ShouldEqual, 0)
+		So(coefficientOfVariation([]float64{10, 20, 30}), ShouldAlmostEqual, 0.4082, 0.0001)
+	})
+}
+
+func createPriorityMockFiles(fs *MapFileSystem) {
+	fs.Files[perDevMockFile] = "Filename Type Size Used Priority\n" +
+		"/dev/sdb1 partition 1000 400 10\n" +
+		"/dev/sdb2 partition 2000 800 10\n" +
+		"/dev/sdc1 partition 1000 500 5\n" +
+		"/dev/sdc2 partition 1000 0 5\n" +
+		"/dev/sdd1 partition 1000 0 -2\n"
+}
@@ -0,0 +1,277 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshot holds copies of collector stats taken when data sources of collection
+// were gathered, metrics are populated from it while other collections go on
+type snapshot struct {
+	stats   map[string]map[string]float64
+	devTags map[string]map[string]string
+}
+
+// groupStats returns stats of given group of metrics, nil if group is not known
+func (swap *swapCollector) groupStats(group string) map[string]float64 {
+	switch group {
+	case ioPrefix:
+		return swap.ioStats
+	case devPrefix:
+		return swap.devStats
+	case combPrefix:
+		return swap.combStats
+	case nodePrefix:
+		return swap.nodeStats
+	case zonePrefix:
+		return swap.zoneStats
+	case kswapdPrefix:
+		return swap.kswapdStats
+	case prioPrefix:
+		return swap.prioStats
+	case cfgdPrefix:
+		return swap.cfgdStats
+	case hibPrefix:
+		return swap.hibStats
+	case featPrefix:
+		return swap.featStats
+	case errPrefix:
+		return swap.errStats
+	case availPrefix:
+		return swap.availStats
+	case telPrefix:
+		return swap.telStats
+	}
+	return nil
+}
+
+// takeSnapshot copies stats of given groups, it must be called with stateMutex held
+func (swap *swapCollector) takeSnapshot(groups map[string]bool) *snapshot {
+	snap := &snapshot{
+		stats:   map[string]map[string]float64{},
+		devTags: map[string]map[string]string{},
+	}
+	for group := range groups {
+		stats := swap.groupStats(group)
+		if stats == nil {
+			continue
+		}
+		copied := make(map[string]float64, len(stats))
+		for k, v := range stats {
+			copied[k] = v
+		}
+		if group == devPrefix {
+			// per device metrics are gathered from several data sources
+			for _, other := range []map[string]float64{swap.devIOStats, swap.devPrioStats} {
+				for k, v := range other {
+					copied[k] = v
+				}
+			}
+		}
+		snap.stats[group] = copied
+	}
+	if groups[devPrefix] {
+		// tags of device are replaced, never modified, after they are gathered
+		for dev, tags := range swap.devTags {
+			snap.devTags[dev] = tags
+		}
+	}
+	return snap
+}
+
+// metrics returns metrics of snapshot matching namespace ns, error if there are none
+func (snap *snapshot) metrics(ns core.Namespace, ts time.Time) ([]plugin.MetricType, error) {
+	var dm []plugin.MetricType
+	var err error
+	group := ns[3].Value
+	stats := snap.stats[group]
+	switch group {
+	case devPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested per device swap stat %s is not available!", stat)
+		}
+		for i := range dm {
+			dm[i].Tags_ = snap.devTags[dm[i].Namespace()[4].Value]
+		}
+	case nodePrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested per node swap stat %s is not available!", stat)
+		}
+	case zonePrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" && ns[5].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value + "/" + ns[6].Value
+			err = fmt.Errorf("Requested per zone swap stat %s is not available!", stat)
+		}
+	case kswapdPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested kswapd stat %s is not available!", stat)
+		}
+	case prioPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested swap priority group stat %s is not available!", stat)
+		}
+	case errPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested collection errors stat %s is not available!", stat)
+		}
+	case telPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value
+			if len(ns) > 5 {
+				stat += "/" + ns[5].Value
+			}
+			err = fmt.Errorf("Requested plugin telemetry stat %s is not available!", stat)
+		}
+	case availPrefix:
+		dm = dynamicMetrics(ns, stats, ts)
+		if len(dm) == 0 && ns[4].Value != "*" {
+			stat := ns[4].Value + "/" + ns[5].Value
+			err = fmt.Errorf("Requested data source stat %s is not available!", stat)
+		}
+	case featPrefix:
+		dm, err = staticMetric(ns, stats, ts, "Requested kernel feature %s is not available!")
+	case hibPrefix:
+		dm, err = staticMetric(ns, stats, ts, "Requested hibernation stat %s is not available!")
+	case cfgdPrefix:
+		dm, err = staticMetric(ns, stats, ts, "Requested configured swap stat %s is not available!")
+	case combPrefix:
+		dm, err = staticMetric(ns, stats, ts, "Requested combined swap stat %s is not available!")
+	case ioPrefix:
+		dm, err = staticMetric(ns, stats, ts, "Requested IO swap stat %s is not available!")
+	default:
+		err = fmt.Errorf("Requested swap stat group %s is not available!", group)
+	}
+	return dm, err
+}
+
+// fork returns working copy of collector which gathers data sources without holding stateMutex
+// and logs with given entry, it must be called with stateMutex held; results of gathered
+// sources are merged back by merge
+func (swap *swapCollector) fork(entry *log.Entry) *swapCollector {
+	src := *swap.src
+	src.counters = &readCounters{}
+	work := &swapCollector{
+		ioStats:       map[string]float64{},
+		devStats:      map[string]float64{},
+		devIOStats:    map[string]float64{},
+		devPrioStats:  map[string]float64{},
+		combStats:     map[string]float64{},
+		nodeStats:     map[string]float64{},
+		zoneStats:     map[string]float64{},
+		kswapdStats:   map[string]float64{},
+		prioStats:     map[string]float64{},
+		cfgdStats:     map[string]float64{},
+		hibStats:      map[string]float64{},
+		featStats:     map[string]float64{},
+		ioHistory:     swap.ioHistory,
+		kswapdHistory: make(map[string]kswapdData, len(swap.kswapdHistory)),
+		diskHistory:   make(map[string]diskData, len(swap.diskHistory)),
+		devices:       swap.devices,
+		headers:       swap.headers,
+		devTags:       swap.devTags,
+		deviceID:      swap.deviceID,
+		src:           &src,
+		features:      swap.features,
+		kconfig:       swap.kconfig,
+		newIOfile:     swap.newIOfile,
+		logger:        swap.logger,
+		entry:         entry,
+	}
+	for pid, data := range swap.kswapdHistory {
+		work.kswapdHistory[pid] = data
+	}
+	for dev, data := range swap.diskHistory {
+		work.diskHistory[dev] = data
+	}
+	return work
+}
+
+// copyResults copies stats and history gathered from data source from one collector to another,
+// maps are replaced, never modified, after source is gathered, so they are not copied deeply
+func copyResults(dst, src *swapCollector, source string) {
+	switch source {
+	case ioPrefix:
+		dst.ioStats = src.ioStats
+		dst.ioHistory = src.ioHistory
+	case devPrefix:
+		dst.devStats = src.devStats
+		dst.devices = src.devices
+		dst.headers = src.headers
+	case prioPrefix:
+		dst.prioStats = src.prioStats
+		dst.devPrioStats = src.devPrioStats
+	case cfgdPrefix:
+		dst.cfgdStats = src.cfgdStats
+	case devIOSource:
+		dst.devIOStats = src.devIOStats
+		dst.diskHistory = src.diskHistory
+	case devTagsSource:
+		dst.devTags = src.devTags
+	case hibPrefix:
+		dst.hibStats = src.hibStats
+	case featPrefix:
+		dst.featStats = src.featStats
+		dst.features = src.features
+		dst.kconfig = src.kconfig
+		dst.newIOfile = src.newIOfile
+	case combPrefix:
+		dst.combStats = src.combStats
+	case nodePrefix:
+		dst.nodeStats = src.nodeStats
+	case zonePrefix:
+		dst.zoneStats = src.zoneStats
+	case kswapdPrefix:
+		dst.kswapdStats = src.kswapdStats
+		dst.kswapdHistory = src.kswapdHistory
+	}
+}
+
+// merge copies results of source gathered by working copy of collector, it must be called
+// with stateMutex held; results of read started before the one already merged are dropped
+func (swap *swapCollector) merge(work *swapCollector, source string, started time.Time) {
+	if started.Before(swap.mergedAt[source]) {
+		return
+	}
+	swap.mergedAt[source] = started
+	copyResults(swap, work, source)
+	if source == combPrefix {
+		swap.setSwapEnabled(work.logEntry(), swap.combStats[combMetrics[15]] == 1)
+	}
+}
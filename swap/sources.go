@@ -0,0 +1,155 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"io"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// sources holds file system data sources of collector are read from and paths
+// of the data sources in it, which are derived from configured procfs, sysfs,
+// devtmpfs and root filesystem paths; reads are counted in telemetry of collector
+type sources struct {
+	FileSystem
+	counters *readCounters
+
+	// Configured procfs, sysfs, devtmpfs and root filesystem paths
+	proc string
+	sys  string
+	dev  string
+	root string
+
+	// Swap IO data source for kernel 2.6+
+	ioNew string
+	// Swap IO data source for kernel <2.6
+	ioOld string
+	// Per device swap data source
+	perDev string
+	// Combined swap data source
+	combined string
+	// Per zone watermarks data source
+	zoneinfo string
+	// Block devices IO data source
+	diskstats string
+	// Per block device data source, used when device is missing in diskstats
+	block string
+	// Mount points data source used to resolve swap files
+	mountinfo string
+	// Block devices by major:minor number data source
+	devBlock string
+	// Per NUMA node data source
+	node string
+	// Kernel command line data source
+	cmdline string
+	// Hibernation resume device data source
+	powerResume string
+	// Hibernation image size data source
+	powerImageSize string
+	// Persistent block device names data source
+	disk string
+	// Kernel release data source
+	osRelease string
+	// Control groups data source
+	cgroups string
+	// Running kernel configuration data source, available with CONFIG_IKCONFIG_PROC
+	kernelConfig string
+	// Directory holding configuration of installed kernels, used when kernelConfig is missing
+	boot string
+	// Runtime zswap state data source
+	zswapEnabled string
+	// Static filesystem table data source
+	fstab string
+	// Directories holding systemd swap units
+	systemdUnits []string
+}
+
+// newSources returns data sources read from fs, placed in given procfs, sysfs,
+// devtmpfs and root filesystem paths, reads are counted in counters if not nil
+func newSources(fs FileSystem, counters *readCounters, proc, sys, dev, root string) *sources {
+	return &sources{
+		FileSystem:     fs,
+		counters:       counters,
+		proc:           proc,
+		sys:            sys,
+		dev:            dev,
+		root:           root,
+		ioNew:          proc + "/vmstat",
+		ioOld:          proc + "/stat",
+		perDev:         proc + "/swaps",
+		combined:       proc + "/meminfo",
+		zoneinfo:       proc + "/zoneinfo",
+		diskstats:      proc + "/diskstats",
+		mountinfo:      proc + "/self/mountinfo",
+		cmdline:        proc + "/cmdline",
+		osRelease:      proc + "/sys/kernel/osrelease",
+		cgroups:        proc + "/cgroups",
+		kernelConfig:   proc + "/config.gz",
+		block:          sys + "/class/block",
+		devBlock:       sys + "/dev/block",
+		node:           sys + "/devices/system/node",
+		powerResume:    sys + "/power/resume",
+		powerImageSize: sys + "/power/image_size",
+		zswapEnabled:   sys + "/module/zswap/parameters/enabled",
+		disk:           dev + "/disk",
+		fstab:          filepath.Join(root, "etc", "fstab"),
+		systemdUnits:   systemdUnitDirs(root),
+		boot:           filepath.Join(root, "boot"),
+	}
+}
+
+// defaultSources returns data sources read from fs in default paths, reads are not counted
+func defaultSources(fs FileSystem) *sources {
+	return newSources(fs, nil, ProcPathDir, SysPathDir, DevPathDir, RootPathDir)
+}
+
+// Open opens data source file for reading, bytes read from it are counted
+func (src *sources) Open(name string) (io.ReadCloser, error) {
+	fd, err := src.FileSystem.Open(name)
+	if err != nil || src.counters == nil {
+		return fd, err
+	}
+	return countingReader{fd, &src.counters.bytesRead}, nil
+}
+
+// skipLine counts line of data source skipped as malformed
+func (src *sources) skipLine() {
+	if src.counters != nil {
+		atomic.AddUint64(&src.counters.skippedLines, 1)
+	}
+}
+
+// countReadError counts failed read of part of data source, which is skipped
+// without failing the whole source, e.g. process which could not be read
+func (src *sources) countReadError() {
+	if src.counters != nil {
+		atomic.AddUint64(&src.counters.readErrors, 1)
+	}
+}
+
+// countReset counts cumulative statistic which went backwards
+func (src *sources) countReset() {
+	if src.counters != nil {
+		atomic.AddUint64(&src.counters.counterResets, 1)
+	}
+}
@@ -22,7 +22,6 @@ limitations under the License.
 package swap
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
@@ -37,15 +36,19 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/intelsdi-x/snap-plugin-utilities/config"
+
+	"github.com/intelsdi-x/snap-plugin-collector-swap/swapstat"
 )
 
 const (
 	// Name of the plugin
 	PluginName = "swap"
 	// Version of the plugin
-	version = 5
+	version = 6
 	// Type of the plugin
 	pluginType = plugin.CollectorPluginType
+	// Number of calls the plugin serves concurrently
+	concurrencyCount = 5
 
 	// Namespace definition
 	vendorPrefix = "intel"
@@ -54,39 +57,92 @@ const (
 	ioPrefix     = "io"
 	devPrefix    = "device"
 	combPrefix   = "all"
+	nodePrefix   = "node"
+	zonePrefix   = "zone"
+	kswapdPrefix = "kswapd"
+	prioPrefix   = "priority"
+	cfgdPrefix   = "configured"
+	hibPrefix    = "hibernation"
+	featPrefix   = "features"
+	errPrefix    = "errors"
+	availPrefix  = "source"
+	telPrefix    = "plugin"
 
 	ProcPathDir = "/proc"
 	ProcPathCfg = "proc_path"
+	SysPathDir  = "/sys"
+	SysPathCfg  = "sys_path"
+	DevPathDir  = "/dev"
+	DevPathCfg  = "dev_path"
+	DeviceIDCfg = "device_id"
+	RootPathDir = "/"
+	RootPathCfg = "root_path"
+	LogLevelCfg = "log_level"
+	TaskNameCfg = "task_name"
+	CacheTTLCfg = "cache_ttl"
+	CacheTTLDef = "0s"
 )
 
 var (
-	// Swap IO data source for kernel 2.6+
-	SourceIOnew = ProcPathDir + "/vmstat"
-	// Swap IO data source for kernel <2.6
-	SourceIOold = ProcPathDir + "/stat"
-	// Per device swap data source
-	SourcePerDev = ProcPathDir + "/swaps"
-	// Combined swap data source
-	SourceCombined = ProcPathDir + "/meminfo"
 	// Swap IO metrics
 	ioMetrics = []string{"in_bytes_per_sec", "in_pages_per_sec", "out_bytes_per_sec", "out_pages_per_sec"}
 	// Swap per device metrics
 	devMetrics = []string{"used_bytes", "used_percent", "free_bytes", "free_percent"}
-	// Swap combined metrics
-	combMetrics = []string{"used_bytes", "used_percent", "free_bytes", "free_percent", "cached_bytes", "cached_percent"}
+	// Swap combined metrics, including memory commit metrics used for swap sizing
+	combMetrics = []string{"used_bytes", "used_percent", "free_bytes", "free_percent", "cached_bytes", "cached_percent",
+		"commit_limit_bytes", "committed_as_bytes", "committed_percent", "commit_headroom_bytes",
+		"mem_total_bytes", "mem_available_bytes", "anon_bytes", "shmem_bytes", "swap_to_ram_ratio", "swap_enabled"}
 )
 
 // SwapCollector holds Linux swap related metrics
 type swapCollector struct {
 	ioStats          map[string]float64
 	devStats         map[string]float64
+	devIOStats       map[string]float64
+	devPrioStats     map[string]float64
 	combStats        map[string]float64
+	nodeStats        map[string]float64
+	zoneStats        map[string]float64
+	kswapdStats      map[string]float64
+	prioStats        map[string]float64
+	cfgdStats        map[string]float64
+	hibStats         map[string]float64
+	featStats        map[string]float64
+	errStats         map[string]float64
+	availStats       map[string]float64
+	telStats         map[string]float64
+	reads            map[string]*sourceRead
+	mergedAt         map[string]time.Time
+	cacheTTL         time.Duration
+	available        map[string]bool
+	swapEnabled      *bool
 	ioHistory        ioData
+	kswapdHistory    map[string]kswapdData
+	diskHistory      map[string]diskData
+	devices          []swapDevice
+	headers          map[string]cachedHeader
+	devTags          map[string]map[string]string
+	deviceID         string
+	src              *sources
+	features         kernelFeatures
+	kconfig          kernelConfig
 	newIOfile        bool
 	initialized      bool
 	initializedMutex *sync.Mutex
+	stateMutex       *sync.Mutex
 	logger           *log.Logger
-	proc_path        string
+	entry            *log.Entry
+}
+
+// swapDevice holds description of swap area listed in src.perDev
+type swapDevice struct {
+	path     string
+	name     string
+	kind     string
+	size     float64
+	used     float64
+	priority string
+	header   *swapHeader
 }
 
 // ioData holds historic data for trend calculation
@@ -104,56 +160,90 @@ func Meta() *plugin.PluginMeta {
 		pluginType,
 		[]string{},
 		[]string{plugin.SnapGOBContentType},
+		// rates are calculated from history kept by plugin instance
 		plugin.RoutingStrategy(plugin.StickyRouting),
-		plugin.ConcurrencyCount(1),
+		plugin.ConcurrencyCount(concurrencyCount),
 	)
 }
 
-// Function to check properness of configuration parameter
-// and set plugin attribute accordingly
-func (swap *swapCollector) setProcPath(cfg interface{}) error {
+// Function to check properness of configuration parameters
+// and set plugin attributes accordingly
+func (swap *swapCollector) setConfig(cfg interface{}) error {
 	swap.initializedMutex.Lock()
 	defer swap.initializedMutex.Unlock()
+	// logging is configured first, so that changes of other settings are logged,
+	// and on every call, so that log level of running plugin can be changed
+	logLevel, err := config.GetConfigItem(cfg, LogLevelCfg)
+	if err == nil && len(logLevel.(string)) > 0 {
+		err := swap.setLogLevel(logLevel.(string))
+		if err != nil {
+			return err
+		}
+	}
 	if swap.initialized {
 		return nil
 	}
-	procPath, err := config.GetConfigItem(cfg, ProcPathCfg)
-	if err == nil && len(procPath.(string)) > 0 {
-		procPathStats, err := os.Stat(procPath.(string))
+	// paths of data sources are derived from configured procfs, sysfs, devtmpfs
+	// and root filesystem paths, which must be directories
+	paths := map[string]*string{}
+	proc, sys, dev, root := swap.src.proc, swap.src.sys, swap.src.dev, swap.src.root
+	paths[ProcPathCfg] = &proc
+	paths[SysPathCfg] = &sys
+	paths[DevPathCfg] = &dev
+	paths[RootPathCfg] = &root
+	for _, name := range []string{ProcPathCfg, SysPathCfg, DevPathCfg, RootPathCfg} {
+		path, err := config.GetConfigItem(cfg, name)
+		if err != nil || len(path.(string)) == 0 {
+			continue
+		}
+		pathStats, err := swap.src.Stat(path.(string))
+		if err != nil {
+			return err
+		}
+		if !pathStats.IsDir() {
+			return errors.New(fmt.Sprintf("%s is not a directory", path.(string)))
+		}
+		if path.(string) != *paths[name] {
+			swap.logEntry().WithFields(log.Fields{"old": *paths[name], "new": path.(string)}).Info(name + " changed")
+		}
+		*paths[name] = path.(string)
+	}
+	swap.src = newSources(swap.src.FileSystem, nil, proc, sys, dev, root)
+	cacheTTL, err := config.GetConfigItem(cfg, CacheTTLCfg)
+	if err == nil && len(cacheTTL.(string)) > 0 {
+		err := swap.setCacheTTL(cacheTTL.(string))
 		if err != nil {
 			return err
 		}
-		if !procPathStats.IsDir() {
-			return errors.New(fmt.Sprintf("%s is not a directory", procPath.(string)))
+	}
+	deviceID, err := config.GetConfigItem(cfg, DeviceIDCfg)
+	if err == nil && len(deviceID.(string)) > 0 {
+		if _, ok := deviceIDLinks[deviceID.(string)]; !ok && deviceID.(string) != deviceIDPath {
+			return fmt.Errorf("%s is not a valid %s, expected one of: %s", deviceID.(string), DeviceIDCfg,
+				strings.Join(deviceIDModes, ", "))
 		}
-		swap.proc_path = procPath.(string)
-		SourceIOnew = procPath.(string) + "/vmstat"
-		SourceIOold = procPath.(string) + "/stat"
-		SourcePerDev = procPath.(string) + "/swaps"
-		SourceCombined = procPath.(string) + "/meminfo"
-		swap.newIOfile = true
+		swap.deviceID = deviceID.(string)
 	}
+	// data sources might have changed, including kernel they describe
+	swap.stateMutex.Lock()
+	swap.kconfig = kernelConfig{}
+	swap.setFeatures()
+	swap.stateMutex.Unlock()
 	swap.initialized = true
 	return nil
 }
 
 // New returns new swap plugin instance
 func NewSwapCollector() *swapCollector {
-	newIOfile := true
-	// Check if we should use new or old source for IO data
-	files := []string{SourcePerDev, SourceCombined}
-	fh, err := os.Open(SourceIOnew)
-	if err != nil {
-		files = append(files, SourceIOold)
-		newIOfile = false
-	}
-	defer fh.Close()
-	// Bail out if not all data sources are accessible
-	for _, f := range files {
-		if !fileOK(f) {
-			return nil
-		}
-	}
+	return NewSwapCollectorFS(OSFileSystem)
+}
+
+// NewSwapCollectorFS returns new swap plugin instance reading data sources from given file system
+func NewSwapCollectorFS(fs FileSystem) *swapCollector {
+	// reads are counted only by working copies gathering data sources, see fork
+	src := newSources(fs, nil, ProcPathDir, SysPathDir, DevPathDir, RootPathDir)
+	kconfig := kernelConfig{}
+	features := detectFeatures(src, &kconfig)
 	ih := ioData{
 		swapIn:    0,
 		swapOut:   0,
@@ -164,153 +254,355 @@ func NewSwapCollector() *swapCollector {
 	s := &swapCollector{
 		ioStats:          map[string]float64{},
 		devStats:         map[string]float64{},
+		devIOStats:       map[string]float64{},
+		devPrioStats:     map[string]float64{},
 		combStats:        map[string]float64{},
+		nodeStats:        map[string]float64{},
+		zoneStats:        map[string]float64{},
+		kswapdStats:      map[string]float64{},
+		prioStats:        map[string]float64{},
+		cfgdStats:        map[string]float64{},
+		hibStats:         map[string]float64{},
+		featStats:        map[string]float64{},
+		errStats:         newErrStats(),
+		availStats:       map[string]float64{},
+		telStats:         newTelStats(),
+		reads:            map[string]*sourceRead{},
+		mergedAt:         map[string]time.Time{},
+		available:        map[string]bool{},
 		ioHistory:        ih,
-		newIOfile:        newIOfile,
+		kswapdHistory:    map[string]kswapdData{},
+		diskHistory:      map[string]diskData{},
+		headers:          map[string]cachedHeader{},
+		devTags:          map[string]map[string]string{},
+		newIOfile:        features.vmstatIO,
+		features:         features,
+		kconfig:          kconfig,
 		logger:           logger,
+		entry:            logger.WithFields(log.Fields{"plugin": PluginName}),
 		initializedMutex: imutex,
-		proc_path:        ProcPathDir,
+		stateMutex:       new(sync.Mutex),
+		deviceID:         deviceIDPath,
+		src:              src,
 	}
+	features.setStats(s.featStats)
+	// plugin starts even if swap is disabled or procfs is restricted,
+	// unavailable sources are retried on every collection
+	s.probeSources()
 	return s
 }
 
-// CollectMetrics returns metrics relevant to Linux swap
+// CollectMetrics returns metrics relevant to Linux swap; failure of one group of metrics
+// or a missing metric does not drop the others, failures are logged and counted in errors
+// group instead and error is returned only if none of requested metrics was collected
 func (swap *swapCollector) CollectMetrics(mts []plugin.MetricType) ([]plugin.MetricType, error) {
-	err := swap.setProcPath(mts[0])
+	err := swap.setConfig(mts[0])
 	if err != nil {
 		return nil, err
 	}
-	// Gather metrics
-	getDevDone := false
-	getCombDone := false
-	getIODone := false
+	start := time.Now()
+	// Gather metrics, every source is read once and groups which failed are not populated;
+	// sources are read by working copy of collector without holding stateMutex, which is
+	// held only while results of read are merged into collector
+	swap.stateMutex.Lock()
+	work := swap.fork(swap.taskEntry(mts))
+	swap.stateMutex.Unlock()
+	failed := map[string]error{}
+	gathered := map[string]bool{}
+	groups := map[string]bool{}
+	gather := func(source string, get func() error) {
+		if gathered[source] {
+			return
+		}
+		gathered[source] = true
+		started := time.Now()
+		swap.stateMutex.Lock()
+		if read := swap.sharedRead(source, started); read != nil {
+			swap.stateMutex.Unlock()
+			<-read.done
+			swap.stateMutex.Lock()
+			// failed read is not shared, source is read again by this collection
+			if read.err == nil {
+				swap.countCacheHit(source)
+				// results of shared read are used by sources gathered next
+				copyResults(work, swap, source)
+				swap.stateMutex.Unlock()
+				return
+			}
+			started = time.Now()
+		}
+		read := swap.startRead(source, started)
+		swap.stateMutex.Unlock()
+		before := work.src.counters.load()
+		err := get()
+		counted := work.src.counters.load().sub(before)
+		swap.stateMutex.Lock()
+		defer swap.stateMutex.Unlock()
+		swap.sourceGathered(source, counted, time.Since(started), err)
+		swap.partsFailed(source, counted.readErrors)
+		swap.finishRead(read, err)
+		if err != nil {
+			failed[source] = err
+			swap.sourceFailed(work.logEntry(), source, err)
+			return
+		}
+		swap.merge(work, source, started)
+		swap.setAvailable(work.logEntry(), source, true, nil)
+	}
 	for _, mt := range mts {
 		ns := mt.Namespace().Strings()
+		groups[ns[3]] = true
 		switch ns[3] {
-		case devPrefix:
-			if !getDevDone {
-				getDevDone = true
-				err := getDevMetrics(swap.devStats)
-				if err != nil {
-					return nil, err
-				}
+		case devPrefix, prioPrefix, cfgdPrefix, hibPrefix:
+			gather(devPrefix, func() error {
+				return getDevMetrics(work)
+			})
+			if err, ok := failed[devPrefix]; ok {
+				// all of these groups are built on top of swap devices
+				failed[ns[3]] = err
+				continue
 			}
-		case combPrefix:
-			if !getCombDone {
-				getCombDone = true
-				err := getCombinedMetrics(swap.combStats)
-				if err != nil {
-					return nil, err
-				}
+			gather(prioPrefix, func() error {
+				getPriorityMetrics(work)
+				return nil
+			})
+			gather(cfgdPrefix, func() error {
+				return getConfiguredMetrics(work)
+			})
+			gather(devIOSource, func() error {
+				return getDevIOMetrics(work)
+			})
+			gather(devTagsSource, func() error {
+				return getDevTags(work)
+			})
+			if ns[3] == hibPrefix {
+				gather(hibPrefix, func() error {
+					return getHibernationMetrics(work)
+				})
 			}
+		case featPrefix:
+			gather(featPrefix, func() error {
+				work.setFeatures()
+				return nil
+			})
+		case combPrefix:
+			gather(combPrefix, func() error {
+				return getCombinedMetrics(work.src, work.combStats)
+			})
 		case ioPrefix:
-			if !getIODone {
-				getIODone = true
-				err := getIOmetrics(swap)
-				if err != nil {
-					return nil, err
-				}
-			}
+			gather(ioPrefix, func() error {
+				return getIOmetrics(work)
+			})
+		case nodePrefix:
+			gather(nodePrefix, func() error {
+				return getNodeMetrics(work.src, work.nodeStats)
+			})
+		case zonePrefix:
+			gather(zonePrefix, func() error {
+				return getZoneMetrics(work.src, work.zoneStats)
+			})
+		case kswapdPrefix:
+			gather(kswapdPrefix, func() error {
+				return getKswapdMetrics(work)
+			})
 		}
 	}
-	//Populate metrics
+	swap.stateMutex.Lock()
+	swap.collected(start, len(gathered) > 0 && len(failed) == 0)
+	snap := swap.takeSnapshot(groups)
+	swap.stateMutex.Unlock()
+	//Populate metrics, errors group is populated last to count metrics missing in this collection
 	metrics := []plugin.MetricType{}
-	var m plugin.MetricType
+	missing := map[string][]error{}
+	errMts := []plugin.MetricType{}
+	var firstErr error
 	ts := time.Now()
-	for _, mt := range mts {
-		ns := mt.Namespace()
-		switch ns[3].Value {
-		case devPrefix:
-			if ns[4].Value == "*" {
-				for k, v := range swap.devStats {
-					metricParts := strings.Split(k, "/")
-					if ns[5].Value == metricParts[1] {
-						ns1 := make([]core.NamespaceElement, len(ns))
-						copy(ns1, ns)
-						ns1[4].Value = metricParts[0]
-						ns1[4].Name = ns[4].Name
-						metrics = append(metrics, plugin.MetricType{
-							Timestamp_: ts,
-							Namespace_: ns1,
-							Data_:      v,
-						})
-					}
+	populate := func(mts []plugin.MetricType) {
+		for _, mt := range mts {
+			ns := mt.Namespace()
+			group := ns[3].Value
+			source := group
+			if group == devPrefix && metricSource[ns[len(ns)-1].Value] != "" {
+				source = metricSource[ns[len(ns)-1].Value]
+			}
+			if err, ok := failed[source]; ok {
+				// already logged and counted while gathering
+				if firstErr == nil {
+					firstErr = err
 				}
 				continue
-			} else {
-				stat := ns[4].Value + "/" + ns[5].Value
-				val, ok := swap.devStats[stat]
-				if !ok {
-					return metrics, fmt.Errorf("Requested per device swap stat %s is not available!", stat)
-				}
-				m.Data_ = val
 			}
-		case combPrefix:
-			stat := ns[4].Value
-			val, ok := swap.combStats[stat]
-			if !ok {
-				return metrics, fmt.Errorf("Requested combined swap stat %s is not available!", stat)
+			dm, err := snap.metrics(ns, ts)
+			if err != nil {
+				missing[group] = append(missing[group], err)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
 			}
-			m.Data_ = val
-		case ioPrefix:
-			stat := ns[4].Value
-			val, ok := swap.ioStats[stat]
-			if !ok {
-				return metrics, fmt.Errorf("Requested IO swap stat %s is not available!", stat)
+			metrics = append(metrics, dm...)
+		}
+	}
+	otherMts := []plugin.MetricType{}
+	for _, mt := range mts {
+		if mt.Namespace()[3].Value == errPrefix {
+			errMts = append(errMts, mt)
+		} else {
+			otherMts = append(otherMts, mt)
+		}
+	}
+	// counters of missing metrics are shared by collections
+	countMissing := func() {
+		for group, errs := range missing {
+			for _, err := range errs {
+				swap.metricMissing(work.logEntry(), group, err)
 			}
-			m.Data_ = val
 		}
-		m.Namespace_ = mt.Namespace()
-		m.Timestamp_ = ts
-		metrics = append(metrics, m)
+		missing = map[string][]error{}
+	}
+	populate(otherMts)
+	swap.stateMutex.Lock()
+	countMissing()
+	if len(errMts) > 0 {
+		snap.stats[errPrefix] = swap.takeSnapshot(map[string]bool{errPrefix: true}).stats[errPrefix]
+	}
+	swap.stateMutex.Unlock()
+	if len(errMts) > 0 {
+		populate(errMts)
+		swap.stateMutex.Lock()
+		countMissing()
+		swap.stateMutex.Unlock()
+	}
+	work.logEntry().WithFields(log.Fields{
+		"requested": len(mts),
+		"collected": len(metrics),
+		"failed":    len(failed),
+		"duration":  time.Since(start).String(),
+	}).Debug("Metrics collected")
+	if len(metrics) == 0 && firstErr != nil {
+		return metrics, firstErr
 	}
 	return metrics, nil
 }
 
+// staticMetric returns metric of group without dynamic elements,
+// notAvailable is format of error returned when metric is missing in stats
+func staticMetric(ns core.Namespace, stats map[string]float64, ts time.Time, notAvailable string) ([]plugin.MetricType, error) {
+	stat := ns[4].Value
+	val, ok := stats[stat]
+	if !ok {
+		return nil, fmt.Errorf(notAvailable, stat)
+	}
+	return []plugin.MetricType{plugin.MetricType{
+		Namespace_: ns,
+		Timestamp_: ts,
+		Data_:      val,
+	}}, nil
+}
+
 // GetMetricTypes returns the metric types relevant to Linux swap
 func (swap *swapCollector) GetMetricTypes(cfg plugin.ConfigType) ([]plugin.MetricType, error) {
-	err := swap.setProcPath(cfg)
+	err := swap.setConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
 	metricTypes := []plugin.MetricType{}
-	// Check if we should use new or old source for IO data
-	files := []string{SourcePerDev, SourceCombined}
-	fh, err := os.Open(SourceIOnew)
-	if err != nil {
-		files = append(files, SourceIOold)
-		swap.newIOfile = false
+	// Check if we should use new or old source for IO data, metrics are listed
+	// even if their sources are not available yet
+	swap.stateMutex.Lock()
+	swap.setFeatures()
+	swap.stateMutex.Unlock()
+	for _, metric := range ioMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, ioPrefix, metric)}
+		metricTypes = append(metricTypes, metricType)
 	}
-	defer fh.Close()
-	// Bail out if not all data sources are accessible
-	for _, f := range files {
-		if !fileOK(f) {
-			return nil, fmt.Errorf("Data source %v not accessible", f)
+	for _, metrics := range [][]string{devMetrics, devIOMetrics, headerMetrics, devPrioMetrics} {
+		for _, metric := range metrics {
+			metricTypes = append(metricTypes, plugin.MetricType{
+				Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, devPrefix).
+					AddDynamicElement("device", "swap device name").
+					AddStaticElement(metric),
+				Description_: "dynamic swap metric: " + metric,
+			})
 		}
 	}
-	fd, err := os.Open(SourcePerDev)
-	// Should never occur as SourcePerDev is part of the file list above
-	// and previous loop already checks availability of file
-	if err != nil {
-		return nil, fmt.Errorf("Failed to open file for reading: %s", SourcePerDev)
+	for _, metric := range combMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, combPrefix, metric)}
+		metricTypes = append(metricTypes, metricType)
 	}
-	defer fd.Close()
-	for _, metric := range ioMetrics {
-		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, ioPrefix, metric)}
+	for _, metric := range cfgdMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, cfgdPrefix, metric)}
+		metricTypes = append(metricTypes, metricType)
+	}
+	for _, metric := range hibMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, hibPrefix, metric)}
+		metricTypes = append(metricTypes, metricType)
+	}
+	for _, metric := range featMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, featPrefix, metric)}
 		metricTypes = append(metricTypes, metricType)
 	}
-	for _, metric := range devMetrics {
+	for _, metric := range nodeMetrics {
 		metricTypes = append(metricTypes, plugin.MetricType{
-			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, devPrefix).
-				AddDynamicElement("device", "swap device name").
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, nodePrefix).
+				AddDynamicElement("node", "NUMA node name").
 				AddStaticElement(metric),
-			Description_: "dynamic swap metric: " + metric,
+			Description_: "dynamic NUMA node metric: " + metric,
 		})
 	}
-	for _, metric := range combMetrics {
-		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, combPrefix, metric)}
+	for _, metric := range zoneMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, zonePrefix).
+				AddDynamicElement("node", "NUMA node name").
+				AddDynamicElement("zone", "memory zone name").
+				AddStaticElement(metric),
+			Description_: "dynamic memory zone metric: " + metric,
+		})
+	}
+	for _, metric := range prioMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, prioPrefix).
+				AddDynamicElement("priority", "swap priority").
+				AddStaticElement(metric),
+			Description_: "dynamic swap priority group metric: " + metric,
+		})
+	}
+	for _, metric := range kswapdMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, kswapdPrefix).
+				AddDynamicElement("node", "NUMA node name served by kswapd thread").
+				AddStaticElement(metric),
+			Description_: "dynamic kswapd metric: " + metric,
+		})
+	}
+	for _, metric := range errMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, errPrefix).
+				AddDynamicElement("source", "data source name").
+				AddStaticElement(metric),
+			Description_: "dynamic collection errors metric: " + metric,
+		})
+	}
+	for _, metric := range availMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, availPrefix).
+				AddDynamicElement("source", "data source name").
+				AddStaticElement(metric),
+			Description_: "dynamic data source metric: " + metric,
+		})
+	}
+	for _, metric := range telMetrics {
+		metricType := plugin.MetricType{Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, telPrefix, metric)}
 		metricTypes = append(metricTypes, metricType)
 	}
+	for _, metric := range telSourceMetrics {
+		metricTypes = append(metricTypes, plugin.MetricType{
+			Namespace_: core.NewNamespace(vendorPrefix, srcPrefix, typePrefix, telPrefix).
+				AddDynamicElement("source", "data source name").
+				AddStaticElement(metric),
+			Description_: "dynamic plugin telemetry metric: " + metric,
+		})
+	}
 	return metricTypes, nil
 }
 
@@ -318,12 +610,52 @@ func (swap *swapCollector) GetMetricTypes(cfg plugin.ConfigType) ([]plugin.Metri
 func (swap *swapCollector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	cp := cpolicy.New()
 	rule, _ := cpolicy.NewStringRule(ProcPathCfg, false, ProcPathDir)
+	sysRule, _ := cpolicy.NewStringRule(SysPathCfg, false, SysPathDir)
+	devRule, _ := cpolicy.NewStringRule(DevPathCfg, false, DevPathDir)
+	deviceIDRule, _ := cpolicy.NewStringRule(DeviceIDCfg, false, deviceIDPath)
+	rootRule, _ := cpolicy.NewStringRule(RootPathCfg, false, RootPathDir)
+	logLevelRule, _ := cpolicy.NewStringRule(LogLevelCfg, false, log.InfoLevel.String())
+	taskNameRule, _ := cpolicy.NewStringRule(TaskNameCfg, false)
+	cacheTTLRule, _ := cpolicy.NewStringRule(CacheTTLCfg, false, CacheTTLDef)
 	node := cpolicy.NewPolicyNode()
-	node.Add(rule)
+	node.Add(rule, sysRule, devRule, deviceIDRule, rootRule, logLevelRule, taskNameRule, cacheTTLRule)
 	cp.Add([]string{vendorPrefix, srcPrefix, PluginName}, node)
 	return cp, nil
 }
 
+// dynamicMetrics returns metrics matching namespace ns built from stats,
+// which are keyed by namespace elements following the group prefix joined with "/"
+func dynamicMetrics(ns core.Namespace, stats map[string]float64, ts time.Time) []plugin.MetricType {
+	metrics := []plugin.MetricType{}
+	for k, v := range stats {
+		metricParts := strings.Split(k, "/")
+		if len(metricParts) != len(ns)-4 {
+			continue
+		}
+		match := true
+		for i, part := range metricParts {
+			if ns[i+4].Value != "*" && ns[i+4].Value != part {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		ns1 := make([]core.NamespaceElement, len(ns))
+		copy(ns1, ns)
+		for i, part := range metricParts {
+			ns1[i+4].Value = part
+		}
+		metrics = append(metrics, plugin.MetricType{
+			Timestamp_: ts,
+			Namespace_: ns1,
+			Data_:      v,
+		})
+	}
+	return metrics
+}
+
 // calcPercentage returns outcome of fraction defined by nominator and denominator in percents
 func calcPercentage(nom, denom float64) float64 {
 	if denom == 0 {
@@ -333,92 +665,78 @@ func calcPercentage(nom, denom float64) float64 {
 	return 100 * nom / denom
 }
 
-func getDevMetrics(dest map[string]float64) error {
-	fd, err := os.Open(SourcePerDev)
+func getDevMetrics(swap *swapCollector) error {
+	buf, err := readSourceBuf(swap.src, swap.src.perDev)
 	if err != nil {
-		return fmt.Errorf("Failed to open file for reading: %s", SourcePerDev)
-	}
-	defer fd.Close()
-	scanner := bufio.NewScanner(fd)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := len(strings.Fields(line))
-		if fields != 5 {
-			continue
-		}
-		dev := strings.Fields(line)[0]
-		if dev == "Filename" {
-			continue
-		}
-		dev = noSlashes(dev)
-		totalS := strings.Fields(line)[2]
-		total, err := strconv.ParseFloat(totalS, 64)
-		if err != nil {
-			return fmt.Errorf("Swap size for %s is not a number: %s", dev, totalS)
-		}
-		usedS := strings.Fields(line)[3]
-		used, err := strconv.ParseFloat(usedS, 64)
-		if err != nil {
-			return fmt.Errorf("Used swap size for %s is not a number: %s", dev, usedS)
-		}
+		return sourceError(swap.src.perDev, err)
+	}
+	defer bufPool.Put(buf)
+	swaps := swapsPool.Get().(*swapstat.SwapsParser)
+	defer swapsPool.Put(swaps)
+	entries, err := swaps.Parse(*buf)
+	for _, lineNo := range swaps.Skipped {
+		swap.logEntry().WithFields(log.Fields{"file": swap.src.perDev, "line": lineNo}).
+			Debug("Skipping malformed line of swap devices list")
+		swap.src.skipLine()
+	}
+	if err != nil {
+		return statError(swap.src.perDev, err, func(path string) string {
+			// device is named by header read by previous gather, if any
+			return deviceName(swap.src, path, swap.deviceID, swap.headers[path].header)
+		})
+	}
+	// stats are built anew, so that devices which were turned off are not reported
+	stats := map[string]float64{}
+	headers := make(map[string]cachedHeader, len(entries))
+	devices := make([]swapDevice, 0, len(entries))
+	for _, entry := range entries {
+		path := entry.Name
+		header := swap.swapHeader(entry, headers)
+		dev := deviceName(swap.src, path, swap.deviceID, header)
+		// sizes are calculated in kB as listed in swaps file
+		total := float64(entry.Size) / 1024.0
+		used := float64(entry.Used) / 1024.0
 		usedBytes := used * 1024.0
 		freeBytes := (total - used) * 1024.0
 		keyUsedBytes := dev + "/" + devMetrics[0]
-		dest[keyUsedBytes] = usedBytes
+		stats[keyUsedBytes] = usedBytes
 		keyUsedPerc := dev + "/" + devMetrics[1]
-		dest[keyUsedPerc] = calcPercentage(used, total)
+		stats[keyUsedPerc] = calcPercentage(used, total)
 		keyFreeBytes := dev + "/" + devMetrics[2]
-		dest[keyFreeBytes] = freeBytes
+		stats[keyFreeBytes] = freeBytes
 		keyFreePerc := dev + "/" + devMetrics[3]
-		dest[keyFreePerc] = calcPercentage(total-used, total)
+		stats[keyFreePerc] = calcPercentage(total-used, total)
+		devices = append(devices, swapDevice{
+			path:     path,
+			name:     dev,
+			kind:     entry.Type,
+			size:     total,
+			used:     used,
+			priority: strconv.Itoa(entry.Priority),
+			header:   header,
+		})
+		setHeaderMetrics(stats, dev, header, total)
 	}
+	swap.devStats = stats
+	swap.headers = headers
+	swap.devices = devices
 	return nil
 }
 
-func getCombinedMetrics(dest map[string]float64) error {
-	fd, err := os.Open(SourceCombined)
+func getCombinedMetrics(src *sources, dest map[string]float64) error {
+	buf, err := readSourceBuf(src, src.combined)
 	if err != nil {
-		return fmt.Errorf("Failed to open following file for reading: %s", SourceCombined)
-	}
-	defer fd.Close()
-	scanner := bufio.NewScanner(fd)
-	scanner.Split(bufio.ScanLines)
-	total := 0.0
-	free := 0.0
-	cached := 0.0
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := len(strings.Fields(line))
-		if fields < 2 {
-			continue
-		}
-		if strings.Fields(line)[0] == "SwapTotal:" {
-			totalS := strings.Fields(line)[1]
-			total, err = strconv.ParseFloat(totalS, 64)
-
-			if err != nil {
-				return fmt.Errorf("SwapTotal is not a number: %s", totalS)
-			}
-		}
-		if strings.Fields(line)[0] == "SwapFree:" {
-			freeS := strings.Fields(line)[1]
-			free, err = strconv.ParseFloat(freeS, 64)
-			if err != nil {
-				return fmt.Errorf("SwapFree is not a number: %s", freeS)
-			}
-		}
-		if strings.Fields(line)[0] == "SwapCached:" {
-			cachedS := strings.Fields(line)[1]
-			cached, err = strconv.ParseFloat(cachedS, 64)
-			if err != nil {
-				return fmt.Errorf("SwapCached is not a number: %s", cachedS)
-			}
-		}
+		return sourceError(src.combined, err)
 	}
-	if total == 0 {
-		fmt.Fprintln(os.Stderr, "Total size of swap is zero, swap might be turned off")
+	defer bufPool.Put(buf)
+	mem, err := swapstat.ParseMemInfo(*buf)
+	if err != nil {
+		return statError(src.combined, err, nil)
 	}
+	// values are calculated in kB as listed in meminfo
+	total := float64(mem.SwapTotal) / 1024.0
+	free := float64(mem.SwapFree) / 1024.0
+	cached := float64(mem.SwapCached) / 1024.0
 	used := total - free
 	totalSwap := total + cached
 	dest[combMetrics[0]] = used * 1024.0
@@ -427,62 +745,48 @@ func getCombinedMetrics(dest map[string]float64) error {
 	dest[combMetrics[3]] = calcPercentage(free, totalSwap)
 	dest[combMetrics[4]] = cached * 1024.0
 	dest[combMetrics[5]] = calcPercentage(cached, totalSwap)
+	commitLimit := float64(mem.CommitLimit)
+	committed := float64(mem.CommittedAS)
+	dest[combMetrics[6]] = commitLimit
+	dest[combMetrics[7]] = committed
+	dest[combMetrics[8]] = calcPercentage(committed, commitLimit)
+	// headroom goes negative when memory is overcommitted beyond CommitLimit
+	dest[combMetrics[9]] = commitLimit - committed
+	dest[combMetrics[10]] = float64(mem.MemTotal)
+	dest[combMetrics[11]] = float64(mem.MemAvailable)
+	dest[combMetrics[12]] = float64(mem.AnonPages)
+	dest[combMetrics[13]] = float64(mem.Shmem)
+	dest[combMetrics[14]] = 0
+	if mem.MemTotal != 0 {
+		dest[combMetrics[14]] = float64(mem.SwapTotal) / float64(mem.MemTotal)
+	}
+	dest[combMetrics[15]] = boolMetric(total > 0)
 	return nil
 }
 
 func getIOmetrics(swap *swapCollector) error {
 	fileToOpen := ""
 	if swap.newIOfile {
-		fileToOpen = SourceIOnew
+		fileToOpen = swap.src.ioNew
 	} else {
-		fileToOpen = SourceIOold
+		fileToOpen = swap.src.ioOld
 	}
-	fd, err := os.Open(fileToOpen)
+	buf, err := readSourceBuf(swap.src, fileToOpen)
 	if err != nil {
-		return fmt.Errorf("Failed to open following file for reading: %s", fileToOpen)
-	}
-	defer fd.Close()
-	scanner := bufio.NewScanner(fd)
-	scanner.Split(bufio.ScanLines)
-	var swapIn float64
-	var swapOut float64
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := len(strings.Fields(line))
-		if swap.newIOfile {
-			if fields < 2 {
-				continue
-			}
-			if strings.Fields(line)[0] == "pswpin" {
-				swapInS := strings.Fields(line)[1]
-				swapIn, err = strconv.ParseFloat(swapInS, 64)
-				if err != nil {
-					return fmt.Errorf("pswpin is not a number: %s", swapInS)
-				}
-			}
-			if strings.Fields(line)[0] == "pswpout" {
-				swapOutS := strings.Fields(line)[1]
-				swapOut, err = strconv.ParseFloat(swapOutS, 64)
-				if err != nil {
-					return fmt.Errorf("pswpout is not a number: %s", swapOutS)
-				}
-
-			}
-		} else {
-			if strings.Fields(line)[0] == "page" {
-				swapInS := strings.Fields(line)[1]
-				swapIn, err = strconv.ParseFloat(swapInS, 64)
-				if err != nil {
-					return fmt.Errorf("Swap in metric is not a number: %s", swapInS)
-				}
-				swapOutS := strings.Fields(line)[2]
-				swapOut, err = strconv.ParseFloat(swapOutS, 64)
-				if err != nil {
-					return fmt.Errorf("Swap out metric is not a number: %s", swapOutS)
-				}
-			}
-		}
+		return sourceError(fileToOpen, err)
 	}
+	defer bufPool.Put(buf)
+	var vmstat swapstat.VMStat
+	if swap.newIOfile {
+		vmstat, err = swapstat.ParseVMStat(*buf)
+	} else {
+		vmstat, err = swapstat.ParseStat(*buf)
+	}
+	if err != nil {
+		return statError(fileToOpen, err, nil)
+	}
+	swapIn := float64(vmstat.PswpIn)
+	swapOut := float64(vmstat.PswpOut)
 	pageSize := float64(os.Getpagesize())
 	oldSwapIn := swap.ioHistory.swapIn
 	oldSwapOut := swap.ioHistory.swapOut
@@ -491,25 +795,30 @@ func getIOmetrics(swap *swapCollector) error {
 	if duration == 0 {
 		return errors.New("Invalid duration time")
 	}
-	swap.ioStats[ioMetrics[0]] = (swapIn - oldSwapIn) * pageSize / duration
-	swap.ioStats[ioMetrics[1]] = (swapIn - oldSwapIn) / duration
-	swap.ioStats[ioMetrics[2]] = (swapOut - oldSwapOut) * pageSize / duration
-	swap.ioStats[ioMetrics[3]] = (swapOut - oldSwapOut) / duration
+	if swapIn < oldSwapIn || swapOut < oldSwapOut {
+		swap.logEntry().WithFields(log.Fields{"file": fileToOpen}).
+			Debug("Swap IO counters went backwards, rates are reset")
+		swap.src.countReset()
+		for _, metric := range ioMetrics {
+			swap.ioStats[metric] = 0
+		}
+	} else {
+		swap.ioStats[ioMetrics[0]] = (swapIn - oldSwapIn) * pageSize / duration
+		swap.ioStats[ioMetrics[1]] = (swapIn - oldSwapIn) / duration
+		swap.ioStats[ioMetrics[2]] = (swapOut - oldSwapOut) * pageSize / duration
+		swap.ioStats[ioMetrics[3]] = (swapOut - oldSwapOut) / duration
+	}
 	swap.ioHistory.swapIn = swapIn
 	swap.ioHistory.swapOut = swapOut
 	swap.ioHistory.timestamp = time.Now()
 	return nil
 }
 
-func fileOK(f string) bool {
-	fh, err := os.Open(f)
+func fileOK(fs FileSystem, f string) bool {
+	fh, err := fs.Open(f)
 	if err != nil {
 		return false
 	}
 	defer fh.Close()
 	return true
 }
-
-func noSlashes(s string) string {
-	return strings.Replace(strings.TrimPrefix(s, "/"), "/", "_", -1)
-}
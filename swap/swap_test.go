@@ -78,34 +78,24 @@ var (
 			Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", "cached_percent"),
 		},
 	}
-	ioNewMockFile  = "/tmp/vmstat"
-	ioOldMockFile  = "/tmp/stat"
-	perDevMockFile = "/tmp/swaps"
-	compMockFile   = "/tmp/meminfo"
+	ioNewMockFile  = ProcPathDir + "/vmstat"
+	ioOldMockFile  = ProcPathDir + "/stat"
+	perDevMockFile = ProcPathDir + "/swaps"
+	compMockFile   = ProcPathDir + "/meminfo"
 )
 
 func TestGetConfigPolicy(t *testing.T) {
-	SourceIOnew = ioNewMockFile
-	SourceIOold = ioOldMockFile
-	SourcePerDev = perDevMockFile
-	SourceCombined = compMockFile
-	createMockFiles()
-	swap := NewSwapCollector()
+	swap := NewSwapCollectorFS(newMockFileSystem())
 	Convey("normal case", t, func() {
 		So(func() { swap.GetConfigPolicy() }, ShouldNotPanic)
 		_, err := swap.GetConfigPolicy()
 		So(err, ShouldBeNil)
 	})
-	deleteMockFiles()
 }
 
 func TestGetMetricTypes(t *testing.T) {
-	SourceIOnew = ioNewMockFile
-	SourceIOold = ioOldMockFile
-	SourcePerDev = perDevMockFile
-	SourceCombined = compMockFile
-	createMockFiles()
-	swap := NewSwapCollector()
+	fs := newMockFileSystem()
+	swap := NewSwapCollectorFS(fs)
 	cfg := plugin.NewPluginConfigType()
 	cfg.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: "/dummy"})
 	Convey("proc_path does not exist", t, func() {
@@ -115,51 +105,41 @@ func TestGetMetricTypes(t *testing.T) {
 		So(m, ShouldBeNil)
 	})
 	cfg = plugin.NewPluginConfigType()
-	cfg.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: "/tmp"})
+	cfg.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: ProcPathDir})
 	Convey("source files available", t, func() {
 		swap.initialized = false
 		m, err := swap.GetMetricTypes(cfg)
 		So(err, ShouldBeNil)
-		// 4 - IO metrics, 4 - dev metrics, 6 - combined metrics
+		// 4 - IO metrics, 18 - dev metrics, 16 - combined metrics, 6 - configured metrics, 7 - hibernation metrics, 7 - kernel feature metrics, 2 - collection errors metrics, 1 - data source metrics, 9 - plugin telemetry metrics, 10 - node metrics, 5 - zone metrics, 2 - priority metrics, 3 - kswapd metrics
 		So(m, ShouldNotBeNil)
-		So(len(m), ShouldEqual, 14)
+		So(len(m), ShouldEqual, 90)
 	})
 	Convey("Dummy IO new source file, should switch to old mode", t, func() {
-		SourceIOnew = ioNewMockFile + "-dummy"
+		delete(fs.Files, ioNewMockFile)
 		m, err := swap.GetMetricTypes(cfg)
 		So(err, ShouldBeNil)
 		So(m, ShouldNotBeNil)
-		So(len(m), ShouldEqual, 14)
+		So(len(m), ShouldEqual, 90)
 	})
-	Convey("Dummy IO new+old source files, should switch to old mode and fail", t, func() {
-		SourceIOnew = ioNewMockFile + "-dummy"
-		SourceIOold = ioOldMockFile + "-dummy"
+	Convey("Dummy IO new+old source files, metrics are listed anyway", t, func() {
+		ioOld := fs.Files[ioOldMockFile]
+		delete(fs.Files, ioOldMockFile)
 		m, err := swap.GetMetricTypes(cfg)
-		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldContainSubstring, "Data source")
-		So(err.Error(), ShouldContainSubstring, "not accessible")
-		So(m, ShouldBeNil)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 90)
+		fs.Files[ioOldMockFile] = ioOld
 	})
-	Convey("dev source file not available", t, func() {
-		SourceIOnew = ioNewMockFile + "-dummy"
-		SourceIOold = ioOldMockFile
-		os.Remove(perDevMockFile)
+	Convey("dev source file not available, metrics are listed anyway", t, func() {
+		delete(fs.Files, perDevMockFile)
 		m, err := swap.GetMetricTypes(cfg)
-		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldContainSubstring, "Data source")
-		So(err.Error(), ShouldContainSubstring, "not accessible")
-		So(m, ShouldBeNil)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 90)
 	})
-	deleteMockFiles()
 }
 
 func TestCollectMetrics(t *testing.T) {
-	SourceIOnew = ioNewMockFile
-	SourceIOold = ioOldMockFile
-	SourcePerDev = perDevMockFile
-	SourceCombined = compMockFile
-	createMockFiles()
-	swap := NewSwapCollector()
+	fs := newMockFileSystem()
+	swap := NewSwapCollectorFS(fs)
 	Convey("proc_path does not exist", t, func() {
 		node := cdata.NewNode()
 		node.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: "/dummy"})
@@ -173,7 +153,7 @@ func TestCollectMetrics(t *testing.T) {
 		So(err, ShouldNotBeNil)
 		So(m, ShouldBeNil)
 	})
-	swap = NewSwapCollector()
+	swap = NewSwapCollectorFS(fs)
 	Convey("source files available", t, func() {
 		m, err := swap.CollectMetrics(mockMts)
 		So(err, ShouldBeNil)
@@ -198,6 +178,31 @@ func TestCollectMetrics(t *testing.T) {
 		So(m, ShouldNotBeNil)
 		So(len(m), ShouldEqual, 1)
 	})
+	Convey("memory commit metrics", t, func() {
+		mts := []plugin.MetricType{}
+		for _, metric := range combMetrics[6:] {
+			mts = append(mts, plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "all", metric),
+			})
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 10)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			vals[metric.Namespace()[4].Value] = metric.Data().(float64)
+		}
+		So(vals["commit_limit_bytes"], ShouldEqual, 200000*1024.0)
+		So(vals["committed_as_bytes"], ShouldEqual, 150000*1024.0)
+		So(vals["committed_percent"], ShouldEqual, 75)
+		So(vals["commit_headroom_bytes"], ShouldEqual, 50000*1024.0)
+		So(vals["mem_total_bytes"], ShouldEqual, 400000*1024.0)
+		So(vals["mem_available_bytes"], ShouldEqual, 300000*1024.0)
+		So(vals["anon_bytes"], ShouldEqual, 100000*1024.0)
+		So(vals["shmem_bytes"], ShouldEqual, 5000*1024.0)
+		So(vals["swap_to_ram_ratio"], ShouldAlmostEqual, 99999/400000.0)
+		So(vals["swap_enabled"], ShouldEqual, 1)
+	})
 	Convey("metrics do not exist", t, func() {
 		mts := []plugin.MetricType{
 			plugin.MetricType{
@@ -234,91 +239,92 @@ func TestCollectMetrics(t *testing.T) {
 		So(len(m), ShouldEqual, 0)
 	})
 	Convey("dev source file not available", t, func() {
-		os.Remove(perDevMockFile)
+		delete(fs.Files, perDevMockFile)
 		m, err := swap.CollectMetrics(mockMts)
+		// IO and combined metrics are still reported
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 10)
+		So(swap.errStats["device/read_errors"], ShouldEqual, 1)
+		m, err = swap.CollectMetrics(mockMts[4:8])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
+		So(swap.errStats["device/read_errors"], ShouldEqual, 2)
 	})
-	deleteMockFiles()
-	SourceIOnew = ioNewMockFile
-	SourceIOold = ioOldMockFile
-	SourcePerDev = perDevMockFile
-	SourceCombined = compMockFile
 	Convey("source files available with errors or specific cases", t, func() {
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"not-an-int", "1010", "2020",
 			"11111", "22222",
 			"33333", "44444",
 			"55555", "6666")
-		swap = NewSwapCollector()
-		m, err := swap.CollectMetrics(mockMts)
+		swap = NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics(mockMts[8:])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "SwapTotal is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "not-an-int", "2020",
 			"11111", "22222",
 			"33333", "44444",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[8:])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "SwapFree is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "not-an-int",
 			"11111", "22222",
 			"33333", "44444",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[8:])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "SwapCached is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"not-an-int", "22222",
 			"33333", "44444",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[:4])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "pswpin is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"11111", "not-an-int",
 			"33333", "44444",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[:4])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "pswpout is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"11111", "22222",
 			"33333", "44444",
 			"not-an-int", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[4:8])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Swap size for")
 		So(err.Error(), ShouldContainSubstring, "is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"11111", "22222",
 			"33333", "44444",
 			"55555", "not-an-int")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[4:8])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Used swap size for")
 		So(err.Error(), ShouldContainSubstring, "is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"0", "1010", "2020",
 			"11111", "22222",
 			"33333", "44444",
@@ -330,50 +336,51 @@ func TestCollectMetrics(t *testing.T) {
 		So(len(m), ShouldEqual, 18)
 
 		swap.newIOfile = false
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"11111", "22222",
 			"not-an-int", "44444",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[:4])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Swap in metric is not a number")
 
-		createMockFilesWithErrors(
+		createMockFilesWithErrors(fs,
 			"99999", "1010", "2020",
 			"11111", "22222",
 			"33333", "not-an-int",
 			"55555", "6666")
-		m, err = swap.CollectMetrics(mockMts)
+		m, err = swap.CollectMetrics(mockMts[:4])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Swap out metric is not a number")
 
 		swap.newIOfile = true
-		err = os.Chmod(SourceCombined, 0)
-		So(err, ShouldBeNil)
-		m, err = swap.CollectMetrics(mockMts)
+		fs.Errors[compMockFile] = os.ErrPermission
+		m, err = swap.CollectMetrics(mockMts[8:])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Failed to open following file for reading")
 
-		err = os.Chmod(SourceIOnew, 0)
-		So(err, ShouldBeNil)
-		m, err = swap.CollectMetrics(mockMts)
+		fs.Errors[ioNewMockFile] = os.ErrPermission
+		m, err = swap.CollectMetrics(mockMts[:4])
 		So(err, ShouldNotBeNil)
-		So(m, ShouldBeNil)
+		So(m, ShouldBeEmpty)
 		So(err.Error(), ShouldContainSubstring, "Failed to open following file for reading")
+
+		// per device metrics are still reported
+		m, err = swap.CollectMetrics(mockMts)
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 8)
+		So(swap.errStats["io/read_errors"], ShouldEqual, 6)
+		So(swap.errStats["all/read_errors"], ShouldEqual, 5)
 	})
-	deleteMockFiles()
 }
 
 func TestHelperRoutines(t *testing.T) {
-	SourceIOnew = ioNewMockFile
-	SourceIOold = ioOldMockFile
-	SourcePerDev = perDevMockFile
-	SourceCombined = compMockFile
-	createMockFiles()
+	fs := newMockFileSystem()
+	fs.Files["/etc/hosts"] = "127.0.0.1 localhost\n"
 	Convey("Helper Routines", t, func() {
 		m := Meta()
 
@@ -382,31 +389,33 @@ func TestHelperRoutines(t *testing.T) {
 		})
 
 		Convey("Set config variables", func() {
-			swap := NewSwapCollector()
+			swap := NewSwapCollectorFS(fs)
 			Convey("Swap collector should not be nil", func() {
 				So(swap, ShouldNotBeNil)
 				cfg := plugin.NewPluginConfigType()
 				cfg.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: "/dummy"})
-				err := swap.setProcPath(cfg)
+				err := swap.setConfig(cfg)
 				Convey("Then error should be reported (no such file or directory)", func() {
 					So(err, ShouldNotBeNil)
 					So(err.Error(), ShouldContainSubstring, "no such file or directory")
 				})
 				cfg = plugin.NewPluginConfigType()
 				cfg.AddItem(ProcPathCfg, ctypes.ConfigValueStr{Value: "/etc/hosts"})
-				err = swap.setProcPath(cfg)
+				err = swap.setConfig(cfg)
 				Convey("Then error should be reported (not a directory)", func() {
 					So(err, ShouldNotBeNil)
 					So(err.Error(), ShouldContainSubstring, "is not a directory")
 				})
-				SourceIOnew = ioNewMockFile + "-dummy"
-				SourceIOold = ioOldMockFile + "-dummy"
-				swap := NewSwapCollector()
-				Convey("Then error should be reported", func() {
-					So(swap, ShouldBeNil)
+				delete(fs.Files, ioNewMockFile)
+				delete(fs.Files, ioOldMockFile)
+				swap := NewSwapCollectorFS(fs)
+				Convey("Then plugin starts and IO source is reported as unavailable", func() {
+					So(swap, ShouldNotBeNil)
+					So(swap.available[ioPrefix], ShouldBeFalse)
+					So(swap.availStats["io/available"], ShouldEqual, 0)
+					So(swap.availStats["device/available"], ShouldEqual, 1)
 				})
-				SourceIOnew = ioNewMockFile
-				SourceIOold = ioOldMockFile
+				createMockFiles(fs)
 			})
 		})
 
@@ -416,12 +425,23 @@ func TestHelperRoutines(t *testing.T) {
 			So(f, ShouldEqual, 0)
 		})
 	})
-	deleteMockFiles()
 }
 
-func createMockFiles() {
+// newMockFileSystem returns in-memory file system holding proper data sources in default paths
+func newMockFileSystem() *MapFileSystem {
+	fs := &MapFileSystem{
+		Files:  map[string]string{},
+		Links:  map[string]string{},
+		Dirs:   map[string]bool{},
+		Errors: map[string]error{},
+	}
+	createMockFiles(fs)
+	return fs
+}
+
+func createMockFiles(fs *MapFileSystem) {
 	// Proper fields
-	createMockFilesWithErrors(
+	createMockFilesWithErrors(fs,
 		"99999", "1010", "2020",
 		"11111", "22222",
 		"33333", "44444",
@@ -429,42 +449,33 @@ func createMockFiles() {
 	)
 }
 
-func createMockFilesWithErrors(
+func createMockFilesWithErrors(fs *MapFileSystem,
 	swapTotal string, swapFree string, swapCached string,
 	swapIn string, swapOut string,
 	pagesIn string, pagesOut string,
 	swapSize string, usedSwapSize string,
 ) {
-	deleteMockFiles()
-	ioNewMockFileCont := []byte(
-		fmt.Sprintf(
-			"pswpin %s\npswpout %s\nbadentry\n",
-			swapIn, swapOut))
-	ioOldMockFileCont := []byte(
-		fmt.Sprintf("page %s %s\nbadentry\n",
-			pagesIn, pagesOut))
-	perDevMockFileCont := []byte(
-		fmt.Sprintf(
-			"Filename Type Size Used Priority\n/dev/sda5 partition %s %s -1\n"+
-				"/dev/sda6 partition  77777 8888   -1\nbadentry\n",
-			swapSize, usedSwapSize))
-	compMockFileCont := []byte(
-		fmt.Sprintf(
-			"SwapTotal: %s kB\nSwapFree: %s kB\nSwapCached: %s kB\nbad-entry\n",
-			swapTotal, swapFree, swapCached))
-	f, _ := os.Create(ioNewMockFile)
-	f.Write(ioNewMockFileCont)
-	f, _ = os.Create(ioOldMockFile)
-	f.Write(ioOldMockFileCont)
-	f, _ = os.Create(perDevMockFile)
-	f.Write(perDevMockFileCont)
-	f, _ = os.Create(compMockFile)
-	f.Write(compMockFileCont)
+	deleteMockFiles(fs)
+	fs.Files[ioNewMockFile] = fmt.Sprintf(
+		"pswpin %s\npswpout %s\nbadentry\n",
+		swapIn, swapOut)
+	fs.Files[ioOldMockFile] = fmt.Sprintf(
+		"page %s %s\nbadentry\n",
+		pagesIn, pagesOut)
+	fs.Files[perDevMockFile] = fmt.Sprintf(
+		"Filename Type Size Used Priority\n/dev/sda5 partition %s %s -1\n"+
+			"/dev/sda6 partition  77777 8888   -1\nbadentry\n",
+		swapSize, usedSwapSize)
+	fs.Files[compMockFile] = fmt.Sprintf(
+		"MemTotal: 400000 kB\nMemAvailable: 300000 kB\nSwapCached: %s kB\nAnonPages: 100000 kB\n"+
+			"Shmem: 5000 kB\nSwapTotal: %s kB\nSwapFree: %s kB\nCommitLimit: 200000 kB\n"+
+			"Committed_AS: 150000 kB\nbad-entry\n",
+		swapCached, swapTotal, swapFree)
 }
 
-func deleteMockFiles() {
-	os.Remove(ioNewMockFile)
-	os.Remove(ioOldMockFile)
-	os.Remove(perDevMockFile)
-	os.Remove(compMockFile)
+func deleteMockFiles(fs *MapFileSystem) {
+	for _, path := range []string{ioNewMockFile, ioOldMockFile, perDevMockFile, compMockFile} {
+		delete(fs.Files, path)
+		delete(fs.Errors, path)
+	}
 }
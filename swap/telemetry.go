@@ -0,0 +1,138 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Plugin self-telemetry metrics
+	telMetrics = []string{"version", "last_success_timestamp", "collect_duration_seconds"}
+	// Plugin self-telemetry metrics reported per data source
+	telSourceMetrics = []string{"duration_seconds", "bytes_read", "parse_errors", "skipped_lines", "counter_resets",
+		"cache_hits"}
+)
+
+// readCounters counts reads done by collector, they are attributed to data sources
+// by the difference before and after the source is gathered
+type readCounters struct {
+	bytesRead     uint64
+	skippedLines  uint64
+	counterResets uint64
+	readErrors    uint64
+}
+
+// load returns current values of counters
+func (c *readCounters) load() readCounters {
+	return readCounters{
+		bytesRead:     atomic.LoadUint64(&c.bytesRead),
+		skippedLines:  atomic.LoadUint64(&c.skippedLines),
+		counterResets: atomic.LoadUint64(&c.counterResets),
+		readErrors:    atomic.LoadUint64(&c.readErrors),
+	}
+}
+
+// sub returns counts of reads done since counters were taken
+func (c readCounters) sub(since readCounters) readCounters {
+	return readCounters{
+		bytesRead:     c.bytesRead - since.bytesRead,
+		skippedLines:  c.skippedLines - since.skippedLines,
+		counterResets: c.counterResets - since.counterResets,
+		readErrors:    c.readErrors - since.readErrors,
+	}
+}
+
+// countingReader counts bytes read from data source file
+type countingReader struct {
+	io.ReadCloser
+	bytesRead *uint64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddUint64(r.bytesRead, uint64(n))
+	return n, err
+}
+
+// openSource opens data source file for reading
+func openSource(fs FileSystem, path string) (io.ReadCloser, error) {
+	return fs.Open(path)
+}
+
+// readSource reads whole data source file
+func readSource(fs FileSystem, path string) ([]byte, error) {
+	fd, err := openSource(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ioutil.ReadAll(fd)
+}
+
+func newTelStats() map[string]float64 {
+	stats := map[string]float64{
+		telMetrics[0]: version,
+		telMetrics[1]: 0,
+		telMetrics[2]: 0,
+	}
+	for _, source := range errSources {
+		for _, metric := range telSourceMetrics {
+			stats[source+"/"+metric] = 0
+		}
+	}
+	return stats
+}
+
+// sourceGathered updates telemetry of data source gathered in given time with given reads counted
+func (swap *swapCollector) sourceGathered(source string, counted readCounters, duration time.Duration, err error) {
+	prefix := source + "/"
+	if _, ok := swap.telStats[prefix+telSourceMetrics[0]]; !ok {
+		return
+	}
+	swap.telStats[prefix+telSourceMetrics[0]] = duration.Seconds()
+	swap.telStats[prefix+telSourceMetrics[1]] += float64(counted.bytesRead)
+	if _, ok := err.(*ParseError); ok {
+		swap.telStats[prefix+telSourceMetrics[2]]++
+	}
+	swap.telStats[prefix+telSourceMetrics[3]] += float64(counted.skippedLines)
+	swap.telStats[prefix+telSourceMetrics[4]] += float64(counted.counterResets)
+}
+
+// countCacheHit counts collection which used result of earlier read of data source
+func (swap *swapCollector) countCacheHit(source string) {
+	key := source + "/" + telSourceMetrics[5]
+	if _, ok := swap.telStats[key]; ok {
+		swap.telStats[key]++
+	}
+}
+
+// collected updates telemetry of whole collection, ok tells if data sources were read and none of them failed
+func (swap *swapCollector) collected(start time.Time, ok bool) {
+	swap.telStats[telMetrics[2]] = time.Since(start).Seconds()
+	if ok {
+		swap.telStats[telMetrics[1]] = float64(time.Now().Unix())
+	}
+}
@@ -0,0 +1,115 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTelemetry(t *testing.T) {
+	fs := newMockFileSystem()
+	telemetry := func(swap *swapCollector, elems ...string) float64 {
+		ns := core.NewNamespace(append([]string{"intel", "procfs", "swap", "plugin"}, elems...)...)
+		m, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{Namespace_: ns}})
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 1)
+		return m[0].Data().(float64)
+	}
+	Convey("telemetry of all sources is reported", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		So(telemetry(swap, "version"), ShouldEqual, version)
+		for _, metric := range telSourceMetrics {
+			m, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "plugin", "*", metric),
+			}})
+			So(err, ShouldBeNil)
+			So(len(m), ShouldEqual, len(errSources))
+		}
+	})
+	Convey("bytes read and skipped lines are counted per source", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		_, err := swap.CollectMetrics(mockMts)
+		So(err, ShouldBeNil)
+		So(telemetry(swap, "all", "bytes_read"), ShouldBeGreaterThan, 0)
+		So(telemetry(swap, "device", "bytes_read"), ShouldBeGreaterThan, 0)
+		// mock list of swap devices has one malformed line
+		So(telemetry(swap, "device", "skipped_lines"), ShouldEqual, 1)
+		So(telemetry(swap, "all", "skipped_lines"), ShouldEqual, 0)
+		So(telemetry(swap, "device", "parse_errors"), ShouldEqual, 0)
+		So(telemetry(swap, "last_success_timestamp"), ShouldBeGreaterThan, 0)
+		_, err = swap.CollectMetrics(mockMts)
+		So(err, ShouldBeNil)
+		So(telemetry(swap, "device", "skipped_lines"), ShouldEqual, 2)
+	})
+	Convey("parse errors are counted and last success is kept", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		So(telemetry(swap, "last_success_timestamp"), ShouldEqual, 0)
+		createMockFilesWithErrors(fs,
+			"not-an-int", "1010", "2020",
+			"11111", "22222",
+			"33333", "44444",
+			"55555", "6666")
+		_, err := swap.CollectMetrics(mockMts[8:])
+		So(err, ShouldNotBeNil)
+		So(telemetry(swap, "all", "parse_errors"), ShouldEqual, 1)
+		So(telemetry(swap, "last_success_timestamp"), ShouldEqual, 0)
+		createMockFiles(fs)
+	})
+	Convey("swap IO counters going backwards are counted as reset", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		_, err := swap.CollectMetrics(mockMts[:4])
+		So(err, ShouldBeNil)
+		swap.ioHistory.swapIn *= 2
+		m, err := swap.CollectMetrics(mockMts[:4])
+		So(err, ShouldBeNil)
+		So(len(m), ShouldEqual, 4)
+		for _, metric := range m {
+			So(metric.Data(), ShouldEqual, 0)
+		}
+		So(telemetry(swap, "io", "counter_resets"), ShouldEqual, 1)
+	})
+	Convey("reads are counted per collector", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		other := NewSwapCollectorFS(fs)
+		_, err := swap.CollectMetrics(mockMts[8:])
+		So(err, ShouldBeNil)
+		read := telemetry(swap, "all", "bytes_read")
+		So(read, ShouldBeGreaterThan, 0)
+		_, err = other.CollectMetrics(mockMts[8:])
+		So(err, ShouldBeNil)
+		So(telemetry(other, "all", "bytes_read"), ShouldEqual, read)
+		So(telemetry(swap, "all", "bytes_read"), ShouldEqual, read)
+	})
+	Convey("unknown telemetry metric", t, func() {
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics([]plugin.MetricType{plugin.MetricType{
+			Namespace_: core.NewNamespace("intel", "procfs", "swap", "plugin", "dummy"),
+		}})
+		So(err, ShouldNotBeNil)
+		So(m, ShouldBeEmpty)
+		So(err.Error(), ShouldContainSubstring, "plugin telemetry stat dummy")
+	})
+}
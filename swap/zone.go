@@ -0,0 +1,116 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Per zone watermark metrics
+	zoneMetrics = []string{"free_pages", "min_pages", "low_pages", "high_pages", "low_watermark_distance_pages"}
+)
+
+// zoneData holds values of single zone section of zoneinfo
+type zoneData struct {
+	key     string
+	values  map[string]float64
+	managed float64
+	hasMgmt bool
+}
+
+// getZoneMetrics parses zoneinfo, where every zone section starts with "Node N, zone NAME"
+// header, and calculates distance of free pages to low watermark which wakes up kswapd
+func getZoneMetrics(src *sources, dest map[string]float64) error {
+	fd, err := openSource(src, src.zoneinfo)
+	if err != nil {
+		return sourceError(src.zoneinfo, err)
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	scanner.Split(bufio.ScanLines)
+	var zone *zoneData
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if fields[0] == "Node" && len(fields) == 4 && fields[2] == "zone" {
+			storeZone(zone, dest)
+			zone = &zoneData{
+				key:    "node" + strings.TrimSuffix(fields[1], ",") + "/" + fields[3],
+				values: map[string]float64{},
+			}
+			continue
+		}
+		if zone == nil {
+			continue
+		}
+		var metric, valS string
+		switch {
+		case fields[0] == "pages" && fields[1] == "free" && len(fields) == 3:
+			metric, valS = zoneMetrics[0], fields[2]
+		case fields[0] == "min" && len(fields) == 2:
+			metric, valS = zoneMetrics[1], fields[1]
+		case fields[0] == "low" && len(fields) == 2:
+			metric, valS = zoneMetrics[2], fields[1]
+		case fields[0] == "high" && len(fields) == 2:
+			metric, valS = zoneMetrics[3], fields[1]
+		case fields[0] == "managed" && len(fields) == 2:
+			managed, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return parseError(src.zoneinfo, lineNo, "Managed pages of zone "+zone.key, fields[1], err)
+			}
+			zone.managed = managed
+			zone.hasMgmt = true
+			continue
+		default:
+			continue
+		}
+		val, err := strconv.ParseFloat(valS, 64)
+		if err != nil {
+			return parseError(src.zoneinfo, lineNo, "Watermark "+metric+" of zone "+zone.key, valS, err)
+		}
+		zone.values[metric] = val
+	}
+	storeZone(zone, dest)
+	return nil
+}
+
+// storeZone puts metrics of populated zone into dest,
+// zones without managed pages have all watermarks zeroed and are skipped
+func storeZone(zone *zoneData, dest map[string]float64) {
+	if zone == nil || (zone.hasMgmt && zone.managed == 0) {
+		return
+	}
+	if _, ok := zone.values[zoneMetrics[0]]; !ok {
+		return
+	}
+	for _, metric := range zoneMetrics[:4] {
+		dest[zone.key+"/"+metric] = zone.values[metric]
+	}
+	dest[zone.key+"/"+zoneMetrics[4]] = zone.values[zoneMetrics[0]] - zone.values[zoneMetrics[2]]
+}
@@ -0,0 +1,129 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swap
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var zoneMockFile = ProcPathDir + "/zoneinfo"
+
+func TestZoneMetrics(t *testing.T) {
+	fs := newMockFileSystem()
+	createZoneMockFile(fs, "3000")
+	swap := NewSwapCollectorFS(fs)
+	Convey("per zone watermark metrics", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "zone", "*", "*", "low_watermark_distance_pages"),
+			},
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "zone", "node0", "Normal", "low_pages"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		// DMA32 and Normal of node0, Normal of node1, unpopulated Movable is skipped
+		So(len(m), ShouldEqual, 4)
+		vals := map[string]float64{}
+		for _, metric := range m {
+			ns := metric.Namespace().Strings()
+			vals[ns[4]+"/"+ns[5]+"/"+ns[6]] = metric.Data().(float64)
+		}
+		So(vals["node0/Normal/low_pages"], ShouldEqual, 2500)
+		So(vals["node0/Normal/low_watermark_distance_pages"], ShouldEqual, 500)
+		So(vals["node0/DMA32/low_watermark_distance_pages"], ShouldEqual, 9000)
+		So(vals["node1/Normal/low_watermark_distance_pages"], ShouldEqual, -100)
+	})
+	Convey("requested zone does not exist", t, func() {
+		mts := []plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "zone", "node0", "Movable", "free_pages"),
+			},
+		}
+		m, err := swap.CollectMetrics(mts)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Requested per zone swap stat")
+		So(len(m), ShouldEqual, 0)
+	})
+	Convey("zoneinfo with errors", t, func() {
+		createZoneMockFile(fs, "not-an-int")
+		swap := NewSwapCollectorFS(fs)
+		m, err := swap.CollectMetrics([]plugin.MetricType{
+			plugin.MetricType{
+				Namespace_: core.NewNamespace("intel", "procfs", "swap", "zone", "*", "*", "free_pages"),
+			},
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Watermark free_pages of zone node0/Normal is not a number")
+		So(m, ShouldBeEmpty)
+	})
+}
+
+func createZoneMockFile(fs *MapFileSystem, normalFree string) {
+	fs.Files[zoneMockFile] = `Node 0, zone    DMA32
+  per-node stats
+      nr_inactive_anon 1234
+      nr_active_anon 5678
+  pages free     10000
+        boost    0
+        min      800
+        low      1000
+        high     1200
+        spanned  1044480
+        present  782288
+        managed  765904
+        protection: (0, 0, 6815, 6815)
+Node 0, zone   Normal
+  pages free     ` + normalFree + `
+        boost    0
+        min      2000
+        low      2500
+        high     3000
+        spanned  1800192
+        present  1800192
+        managed  1760000
+        protection: (0, 0, 0, 0)
+Node 0, zone  Movable
+  pages free     0
+        min      0
+        low      0
+        high     0
+        spanned  0
+        present  0
+        managed  0
+Node 1, zone   Normal
+  per-node stats
+      nr_inactive_anon 4321
+  pages free     1900
+        min      1500
+        low      2000
+        high     2500
+        spanned  2097152
+        present  2097152
+        managed  2050000
+`
+}
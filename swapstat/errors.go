@@ -0,0 +1,107 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swapstat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrMissingField is held by ParseError when line of file does not have expected field,
+// e.g. the file is truncated
+var ErrMissingField = errors.New("field is missing")
+
+// SourceError is returned when file cannot be opened or read,
+// Err holds the underlying error, e.g. *os.PathError with ENOENT or EACCES
+type SourceError struct {
+	File string
+	Err  error
+}
+
+func (e *SourceError) Error() string {
+	if pathErr, ok := e.Err.(*os.PathError); ok {
+		return fmt.Sprintf("Failed to read %s: %s", e.File, pathErr.Err)
+	}
+	return fmt.Sprintf("Failed to read %s: %s", e.File, e.Err)
+}
+
+// Cause returns the underlying error
+func (e *SourceError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when value of file is malformed, Line is 1-based number of line
+// holding the value and Device is path of swap area the value belongs to, if any
+type ParseError struct {
+	File   string
+	Line   int
+	Field  string
+	Device string
+	Value  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Field
+	if e.Device != "" {
+		msg += " for " + e.Device
+	}
+	if e.Err == ErrMissingField {
+		msg += " is missing"
+	} else {
+		msg += " is not a number: " + e.Value
+	}
+	switch {
+	case e.File != "" && e.Line > 0:
+		msg += fmt.Sprintf(" (%s:%d)", e.File, e.Line)
+	case e.File != "":
+		msg += fmt.Sprintf(" (%s)", e.File)
+	}
+	return msg
+}
+
+// Cause returns the underlying error, e.g. *strconv.NumError
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error, e.g. *strconv.NumError
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error of SourceError or ParseError, err itself otherwise
+func Cause(err error) error {
+	switch e := err.(type) {
+	case *SourceError:
+		return e.Err
+	case *ParseError:
+		return e.Err
+	}
+	return err
+}
@@ -0,0 +1,355 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swapstat
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxFields is number of line fields kept by parsers without allocation
+const maxFields = 8
+
+// SwapDevice is swap area listed in /proc/swaps
+type SwapDevice struct {
+	// Path of swap device or file
+	Name string
+	// Type of swap area, "partition" or "file"
+	Type string
+	// Size of swap area in bytes
+	Size uint64
+	// Used space of swap area in bytes
+	Used uint64
+	// Priority of swap area, higher is used first
+	Priority int
+}
+
+// MemInfo holds fields of /proc/meminfo related to swap, in bytes
+type MemInfo struct {
+	SwapTotal    uint64
+	SwapFree     uint64
+	SwapCached   uint64
+	CommitLimit  uint64
+	CommittedAS  uint64
+	MemTotal     uint64
+	MemAvailable uint64
+	AnonPages    uint64
+	Shmem        uint64
+}
+
+// VMStat holds swap IO counters, in pages
+type VMStat struct {
+	PswpIn  uint64
+	PswpOut uint64
+}
+
+// field returns field of m listed in /proc/meminfo with given name, nil if not used
+func (m *MemInfo) field(name []byte) *uint64 {
+	switch string(name) {
+	case "SwapTotal:":
+		return &m.SwapTotal
+	case "SwapFree:":
+		return &m.SwapFree
+	case "SwapCached:":
+		return &m.SwapCached
+	case "CommitLimit:":
+		return &m.CommitLimit
+	case "Committed_AS:":
+		return &m.CommittedAS
+	case "MemTotal:":
+		return &m.MemTotal
+	case "MemAvailable:":
+		return &m.MemAvailable
+	case "AnonPages:":
+		return &m.AnonPages
+	case "Shmem:":
+		return &m.Shmem
+	}
+	return nil
+}
+
+// ParseMemInfo parses content of /proc/meminfo, fields missing in content are zero
+func ParseMemInfo(content []byte) (MemInfo, error) {
+	var m MemInfo
+	var fieldsBuf [maxFields][]byte
+	lineNo := 0
+	for len(content) > 0 {
+		var line []byte
+		line, content = nextLine(content)
+		lineNo++
+		fields := splitFields(line, fieldsBuf[:0])
+		if len(fields) == 0 {
+			continue
+		}
+		dest := m.field(fields[0])
+		if dest == nil {
+			continue
+		}
+		name := fields[0][:len(fields[0])-1]
+		if len(fields) < 2 {
+			return m, &ParseError{Line: lineNo, Field: string(name), Err: ErrMissingField}
+		}
+		val, err := parseUint(fields[1])
+		if err != nil {
+			return m, &ParseError{Line: lineNo, Field: string(name), Value: string(fields[1]), Err: err}
+		}
+		// values are listed in kB
+		*dest = val * 1024
+	}
+	return m, nil
+}
+
+// ParseVMStat parses swap IO counters from content of /proc/vmstat
+func ParseVMStat(content []byte) (VMStat, error) {
+	var s VMStat
+	var fieldsBuf [maxFields][]byte
+	lineNo := 0
+	for len(content) > 0 {
+		var line []byte
+		line, content = nextLine(content)
+		lineNo++
+		fields := splitFields(line, fieldsBuf[:0])
+		if len(fields) == 0 {
+			continue
+		}
+		var dest *uint64
+		switch string(fields[0]) {
+		case "pswpin":
+			dest = &s.PswpIn
+		case "pswpout":
+			dest = &s.PswpOut
+		default:
+			continue
+		}
+		if len(fields) < 2 {
+			return s, &ParseError{Line: lineNo, Field: string(fields[0]), Err: ErrMissingField}
+		}
+		val, err := parseUint(fields[1])
+		if err != nil {
+			return s, &ParseError{Line: lineNo, Field: string(fields[0]), Value: string(fields[1]), Err: err}
+		}
+		*dest = val
+	}
+	return s, nil
+}
+
+// ParseStat parses swap IO counters from "page" line of /proc/stat of kernels older than 2.6
+func ParseStat(content []byte) (VMStat, error) {
+	var s VMStat
+	var fieldsBuf [maxFields][]byte
+	lineNo := 0
+	for len(content) > 0 {
+		var line []byte
+		line, content = nextLine(content)
+		lineNo++
+		fields := splitFields(line, fieldsBuf[:0])
+		if len(fields) == 0 || string(fields[0]) != "page" {
+			continue
+		}
+		if len(fields) < 2 {
+			return s, &ParseError{Line: lineNo, Field: "Swap in metric", Err: ErrMissingField}
+		}
+		if len(fields) < 3 {
+			return s, &ParseError{Line: lineNo, Field: "Swap out metric", Err: ErrMissingField}
+		}
+		val, err := parseUint(fields[1])
+		if err != nil {
+			return s, &ParseError{Line: lineNo, Field: "Swap in metric", Value: string(fields[1]), Err: err}
+		}
+		s.PswpIn = val
+		val, err = parseUint(fields[2])
+		if err != nil {
+			return s, &ParseError{Line: lineNo, Field: "Swap out metric", Value: string(fields[2]), Err: err}
+		}
+		s.PswpOut = val
+	}
+	return s, nil
+}
+
+// ParseSwaps parses content of /proc/swaps
+func ParseSwaps(content []byte) ([]SwapDevice, error) {
+	devices, err := (&SwapsParser{}).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// SwapsParser parses content of /proc/swaps reusing devices and strings of previous parse,
+// so that parsing does not allocate as long as the list of swap areas does not change;
+// it must not be used concurrently
+type SwapsParser struct {
+	devices []SwapDevice
+	strs    map[string]string
+	// Skipped holds line numbers of malformed lines skipped by the latest parse
+	Skipped []int
+}
+
+// Parse returns swap areas listed in content, they are valid until the next parse
+func (p *SwapsParser) Parse(content []byte) ([]SwapDevice, error) {
+	if p.strs == nil || len(p.strs) > 1024 {
+		p.strs = map[string]string{}
+	}
+	p.devices = p.devices[:0]
+	p.Skipped = p.Skipped[:0]
+	var fieldsBuf [maxFields][]byte
+	lineNo := 0
+	for len(content) > 0 {
+		var line []byte
+		line, content = nextLine(content)
+		lineNo++
+		fields := splitFields(line, fieldsBuf[:0])
+		if len(fields) != 5 {
+			if len(fields) > 0 {
+				p.Skipped = append(p.Skipped, lineNo)
+			}
+			continue
+		}
+		if string(fields[0]) == "Filename" {
+			continue
+		}
+		device := SwapDevice{
+			Name: p.path(fields[0]),
+			Type: p.intern(fields[1]),
+		}
+		size, err := parseUint(fields[2])
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Field: "Swap size", Device: device.Name, Value: string(fields[2]), Err: err}
+		}
+		used, err := parseUint(fields[3])
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Field: "Used swap size", Device: device.Name, Value: string(fields[3]), Err: err}
+		}
+		priority, err := parseInt(fields[4])
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Field: "Priority", Device: device.Name, Value: string(fields[4]), Err: err}
+		}
+		// sizes are listed in kB
+		device.Size = size * 1024
+		device.Used = used * 1024
+		device.Priority = priority
+		p.devices = append(p.devices, device)
+	}
+	return p.devices, nil
+}
+
+// intern returns string of b, strings seen by previous parses are reused
+func (p *SwapsParser) intern(b []byte) string {
+	if s, ok := p.strs[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	p.strs[s] = s
+	return s
+}
+
+// path returns unescaped path of swap area listed as b, paths seen by previous parses are reused
+func (p *SwapsParser) path(b []byte) string {
+	if s, ok := p.strs[string(b)]; ok {
+		return s
+	}
+	s := UnescapeOctal(string(b))
+	p.strs[string(b)] = s
+	return s
+}
+
+// UnescapeOctal decodes octal escape sequences, e.g. \040 for space, used by procfs in paths
+func UnescapeOctal(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				out = append(out, byte(c))
+				i += 3
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// nextLine returns first line of content and content following it
+func nextLine(content []byte) ([]byte, []byte) {
+	for i, c := range content {
+		if c == '\n' {
+			return content[:i], content[i+1:]
+		}
+	}
+	return content, nil
+}
+
+// splitFields splits line on spaces and tabs in single pass, fields are appended to dst,
+// which is expected to have capacity of maxFields
+func splitFields(line []byte, dst [][]byte) [][]byte {
+	start := -1
+	for i, c := range line {
+		if c == ' ' || c == '\t' {
+			if start >= 0 {
+				dst = append(dst, line[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		dst = append(dst, line[start:])
+	}
+	return dst
+}
+
+// parseUint parses decimal number, numbers of up to 19 digits are converted without allocation
+func parseUint(b []byte) (uint64, error) {
+	if len(b) == 0 || len(b) > 19 {
+		return strconv.ParseUint(string(b), 10, 64)
+	}
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return strconv.ParseUint(string(b), 10, 64)
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n, nil
+}
+
+// parseInt parses signed decimal number without allocation
+func parseInt(b []byte) (int, error) {
+	if len(b) > 1 && b[0] == '-' {
+		n, err := parseUint(b[1:])
+		if err != nil || n > 1<<31 {
+			return strconv.Atoi(string(b))
+		}
+		return -int(n), nil
+	}
+	n, err := parseUint(b)
+	if err != nil || n > 1<<31-1 {
+		return strconv.Atoi(string(b))
+	}
+	return int(n), nil
+}
@@ -0,0 +1,226 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swapstat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Fixtures of hosts with many swap areas and long meminfo and vmstat files
+var (
+	largeSwaps = func() []byte {
+		b := &bytes.Buffer{}
+		b.WriteString("Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n")
+		for i := 0; i < 256; i++ {
+			fmt.Fprintf(b, "/dev/mapper/vg-swap%d\t\tpartition\t%d\t%d\t%d\n", i, 8388604, i*1024, -2-i)
+		}
+		return b.Bytes()
+	}()
+	largeMeminfo = func() []byte {
+		b := &bytes.Buffer{}
+		for i := 0; i < 64; i++ {
+			fmt.Fprintf(b, "Field%d:\t%16d kB\n", i, i*1000)
+		}
+		for _, field := range []string{"SwapTotal", "SwapFree", "SwapCached", "CommitLimit", "Committed_AS",
+			"MemTotal", "MemAvailable", "AnonPages", "Shmem"} {
+			fmt.Fprintf(b, "%s:\t%16d kB\n", field, 16384000)
+		}
+		return b.Bytes()
+	}()
+	largeVmstat = func() []byte {
+		b := &bytes.Buffer{}
+		for i := 0; i < 160; i++ {
+			fmt.Fprintf(b, "counter_%d %d\n", i, i*123456789)
+		}
+		b.WriteString("pswpin 123456789\npswpout 987654321\n")
+		return b.Bytes()
+	}()
+)
+
+func TestParsers(t *testing.T) {
+	Convey("fields are split on spaces and tabs", t, func() {
+		var buf [maxFields][]byte
+		fields := splitFields([]byte(" /dev/sda5\t partition  99999\t6666 -1 "), buf[:0])
+		So(len(fields), ShouldEqual, 5)
+		So(string(fields[0]), ShouldEqual, "/dev/sda5")
+		So(string(fields[4]), ShouldEqual, "-1")
+		So(splitFields([]byte(" \t "), buf[:0]), ShouldBeEmpty)
+		line, rest := nextLine([]byte("a b\nc"))
+		So(string(line), ShouldEqual, "a b")
+		So(string(rest), ShouldEqual, "c")
+	})
+	Convey("numbers are parsed as by strconv", t, func() {
+		for _, s := range []string{"0", "42", "9007199254740993", "18446744073709551615",
+			"1234567890123456789", "12345678901234567890", "18446744073709551616", "-1", "1.5", "", "not-an-int", "12a"} {
+			expected, expectedErr := strconv.ParseUint(s, 10, 64)
+			val, err := parseUint([]byte(s))
+			So(val, ShouldEqual, expected)
+			So(err == nil, ShouldEqual, expectedErr == nil)
+		}
+		for _, s := range []string{"0", "-1", "-2147483648", "2147483647", "-", "--1", "+1", "x"} {
+			expected, expectedErr := strconv.Atoi(s)
+			val, err := parseInt([]byte(s))
+			So(val, ShouldEqual, expected)
+			So(err == nil, ShouldEqual, expectedErr == nil)
+		}
+	})
+	Convey("octal escapes of paths are decoded", t, func() {
+		So(UnescapeOctal(`/mnt/a\040b\134c`), ShouldEqual, `/mnt/a b\c`)
+		So(UnescapeOctal(`/mnt/a\04`), ShouldEqual, `/mnt/a\04`)
+		So(UnescapeOctal("/swapfile"), ShouldEqual, "/swapfile")
+	})
+	Convey("meminfo is parsed", t, func() {
+		m, err := ParseMemInfo([]byte("MemTotal: 400000 kB\nSwapTotal: 99999 kB\nbad-entry\n\nCommitted_AS: 5000 kB"))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, MemInfo{MemTotal: 400000 * 1024, SwapTotal: 99999 * 1024, CommittedAS: 5000 * 1024})
+		_, err = ParseMemInfo([]byte("MemTotal: 400000 kB\nSwapFree: x kB\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "SwapFree is not a number: x")
+		So(err.(*ParseError).Line, ShouldEqual, 2)
+		_, ok := Cause(err).(*strconv.NumError)
+		So(ok, ShouldBeTrue)
+	})
+	Convey("vmstat and stat are parsed", t, func() {
+		s, err := ParseVMStat([]byte("pgpgin 1\npswpin 11111\n\npswpout 22222\nbadentry\n"))
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, VMStat{PswpIn: 11111, PswpOut: 22222})
+		s, err = ParseStat([]byte("cpu 1 2 3\n\npage 33333 44444\nbadentry\n"))
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, VMStat{PswpIn: 33333, PswpOut: 44444})
+		_, err = ParseStat([]byte("page 33333 x\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Swap out metric is not a number")
+		_, err = ParseStat([]byte("page 33333\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Swap out metric is missing")
+		_, err = ParseVMStat([]byte("pswpin 11111\npswpout\n"))
+		So(err, ShouldNotBeNil)
+		So(err.(*ParseError).Err, ShouldEqual, ErrMissingField)
+		So(err.(*ParseError).Line, ShouldEqual, 2)
+	})
+	Convey("swaps are parsed", t, func() {
+		p := &SwapsParser{}
+		devices, err := p.Parse([]byte("Filename Type Size Used Priority\n/dev/sda5 partition 99999 6666 -1\n" +
+			"/var/swap\\040file file 1024 0 5\nbadentry\n"))
+		So(err, ShouldBeNil)
+		So(devices, ShouldResemble, []SwapDevice{
+			SwapDevice{Name: "/dev/sda5", Type: "partition", Size: 99999 * 1024, Used: 6666 * 1024, Priority: -1},
+			SwapDevice{Name: "/var/swap file", Type: "file", Size: 1024 * 1024, Used: 0, Priority: 5},
+		})
+		So(p.Skipped, ShouldResemble, []int{4})
+		_, err = ParseSwaps([]byte("/dev/sda5 partition 99999 x -1\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Used swap size for /dev/sda5 is not a number: x")
+		So(err.(*ParseError).Device, ShouldEqual, "/dev/sda5")
+	})
+	Convey("parsers do not allocate in steady state", t, func() {
+		p := &SwapsParser{}
+		p.Parse(largeSwaps)
+		So(testing.AllocsPerRun(10, func() { ParseMemInfo(largeMeminfo) }), ShouldEqual, 0)
+		So(testing.AllocsPerRun(10, func() { ParseVMStat(largeVmstat) }), ShouldEqual, 0)
+		So(testing.AllocsPerRun(10, func() { p.Parse(largeSwaps) }), ShouldEqual, 0)
+	})
+}
+
+// referenceParse parses "name value..." lines with strings.Fields, as the plugin used to do
+func referenceParse(content []byte, dest map[string]float64) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.Fields(line)) < 2 {
+			continue
+		}
+		val, err := strconv.ParseFloat(strings.Fields(line)[1], 64)
+		if err == nil {
+			dest[strings.Fields(line)[0]] = val
+		}
+	}
+}
+
+func BenchmarkParseMemInfo(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseMemInfo(largeMeminfo)
+	}
+}
+
+func BenchmarkParseMemInfoFields(b *testing.B) {
+	b.ReportAllocs()
+	dest := map[string]float64{}
+	for i := 0; i < b.N; i++ {
+		referenceParse(largeMeminfo, dest)
+	}
+}
+
+func BenchmarkParseVMStat(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseVMStat(largeVmstat)
+	}
+}
+
+func BenchmarkParseVMStatFields(b *testing.B) {
+	b.ReportAllocs()
+	dest := map[string]float64{}
+	for i := 0; i < b.N; i++ {
+		referenceParse(largeVmstat, dest)
+	}
+}
+
+func BenchmarkParseSwaps(b *testing.B) {
+	b.ReportAllocs()
+	p := &SwapsParser{}
+	for i := 0; i < b.N; i++ {
+		p.Parse(largeSwaps)
+	}
+}
+
+func BenchmarkParseSwapsFields(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scanner := bufio.NewScanner(bytes.NewReader(largeSwaps))
+		devices := []SwapDevice{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if len(strings.Fields(line)) != 5 || strings.Fields(line)[0] == "Filename" {
+				continue
+			}
+			size, _ := strconv.ParseUint(strings.Fields(line)[2], 10, 64)
+			used, _ := strconv.ParseUint(strings.Fields(line)[3], 10, 64)
+			priority, _ := strconv.Atoi(strings.Fields(line)[4])
+			devices = append(devices, SwapDevice{
+				Name:     strings.Fields(line)[0],
+				Type:     strings.Fields(line)[1],
+				Size:     size * 1024,
+				Used:     used * 1024,
+				Priority: priority,
+			})
+		}
+	}
+}
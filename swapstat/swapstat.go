@@ -0,0 +1,239 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package swapstat reads Linux swap statistics from procfs, independently of Snap.
+//
+// Data sources are parsed without allocation in steady state, so the package is suitable
+// for agents collecting swap statistics frequently:
+//
+//	prev, _ := swapstat.ReadSnapshot("/proc")
+//	time.Sleep(time.Second)
+//	cur, _ := swapstat.ReadSnapshot("/proc")
+//	delta, err := cur.Delta(prev)
+//	if err == nil {
+//		rate := delta.Rate(os.Getpagesize())
+//		fmt.Println(rate.InBytesPerSec, rate.OutBytesPerSec)
+//	}
+package swapstat
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// ProcPath is the default mount point of procfs
+	ProcPath = "/proc"
+	// SwapsFile is file of procfs listing swap areas
+	SwapsFile = "swaps"
+	// MemInfoFile is file of procfs with memory usage, including swap usage
+	MemInfoFile = "meminfo"
+	// VMStatFile is file of procfs with virtual memory counters, including swap IO of kernel 2.6+
+	VMStatFile = "vmstat"
+	// StatFile is file of procfs with swap IO of kernels older than 2.6
+	StatFile = "stat"
+)
+
+// ErrCounterReset is returned by Delta when counters went backwards, e.g. after 32 bit counter wrap
+var ErrCounterReset = errors.New("swap IO counters went backwards")
+
+// Snapshot holds swap statistics read at one moment
+type Snapshot struct {
+	// Time when statistics were read
+	Time    time.Time
+	Devices []SwapDevice
+	MemInfo MemInfo
+	VMStat  VMStat
+}
+
+// Delta holds change of swap IO counters between two snapshots
+type Delta struct {
+	PagesIn  uint64
+	PagesOut uint64
+	Duration time.Duration
+}
+
+// Rate holds swap IO per second
+type Rate struct {
+	InPagesPerSec  float64
+	OutPagesPerSec float64
+	InBytesPerSec  float64
+	OutBytesPerSec float64
+}
+
+// ReadSnapshot reads swap areas, memory usage and swap IO counters from procfs mounted at procPath,
+// swap IO is read from StatFile if VMStatFile is not available
+func ReadSnapshot(procPath string) (*Snapshot, error) {
+	devices, err := ReadSwaps(filepath.Join(procPath, SwapsFile))
+	if err != nil {
+		return nil, err
+	}
+	memInfo, err := ReadMemInfo(filepath.Join(procPath, MemInfoFile))
+	if err != nil {
+		return nil, err
+	}
+	vmStat, err := ReadVMStat(filepath.Join(procPath, VMStatFile))
+	if err != nil && os.IsNotExist(Cause(err)) {
+		vmStat, err = ReadStat(filepath.Join(procPath, StatFile))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Time:    time.Now(),
+		Devices: devices,
+		MemInfo: memInfo,
+		VMStat:  vmStat,
+	}, nil
+}
+
+// Delta returns change of swap IO counters since prev snapshot,
+// ErrCounterReset is returned if counters went backwards
+func (s *Snapshot) Delta(prev *Snapshot) (Delta, error) {
+	duration := s.Time.Sub(prev.Time)
+	if duration <= 0 {
+		return Delta{}, errors.New("snapshot is not newer than previous one")
+	}
+	if s.VMStat.PswpIn < prev.VMStat.PswpIn || s.VMStat.PswpOut < prev.VMStat.PswpOut {
+		return Delta{}, ErrCounterReset
+	}
+	return Delta{
+		PagesIn:  s.VMStat.PswpIn - prev.VMStat.PswpIn,
+		PagesOut: s.VMStat.PswpOut - prev.VMStat.PswpOut,
+		Duration: duration,
+	}, nil
+}
+
+// Rate returns swap IO per second for pages of given size, e.g. os.Getpagesize()
+func (d Delta) Rate(pageSize int) Rate {
+	seconds := d.Duration.Seconds()
+	if seconds <= 0 {
+		return Rate{}
+	}
+	return Rate{
+		InPagesPerSec:  float64(d.PagesIn) / seconds,
+		OutPagesPerSec: float64(d.PagesOut) / seconds,
+		InBytesPerSec:  float64(d.PagesIn) * float64(pageSize) / seconds,
+		OutBytesPerSec: float64(d.PagesOut) * float64(pageSize) / seconds,
+	}
+}
+
+// ReadSwaps reads swap areas listed in file with format of /proc/swaps
+func ReadSwaps(path string) ([]SwapDevice, error) {
+	var devices []SwapDevice
+	err := readFile(path, func(content []byte) error {
+		var err error
+		devices, err = ParseSwaps(content)
+		return err
+	})
+	return devices, err
+}
+
+// ReadMemInfo reads memory usage from file with format of /proc/meminfo
+func ReadMemInfo(path string) (MemInfo, error) {
+	var memInfo MemInfo
+	err := readFile(path, func(content []byte) error {
+		var err error
+		memInfo, err = ParseMemInfo(content)
+		return err
+	})
+	return memInfo, err
+}
+
+// ReadVMStat reads swap IO counters from file with format of /proc/vmstat
+func ReadVMStat(path string) (VMStat, error) {
+	var vmStat VMStat
+	err := readFile(path, func(content []byte) error {
+		var err error
+		vmStat, err = ParseVMStat(content)
+		return err
+	})
+	return vmStat, err
+}
+
+// ReadStat reads swap IO counters from file with format of /proc/stat of kernels older than 2.6
+func ReadStat(path string) (VMStat, error) {
+	var vmStat VMStat
+	err := readFile(path, func(content []byte) error {
+		var err error
+		vmStat, err = ParseStat(content)
+		return err
+	})
+	return vmStat, err
+}
+
+// Buffers files are read into
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 16*1024)
+		return &buf
+	},
+}
+
+// readFile reads whole file into buffer shared by reads and passes its content to parse,
+// path is set in errors returned by parse
+func readFile(path string, parse func(content []byte) error) error {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	content, err := ReadFile(path, (*buf)[:0])
+	*buf = content
+	if err != nil {
+		return &SourceError{File: path, Err: err}
+	}
+	err = parse(content)
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.File = path
+	}
+	return err
+}
+
+// ReadFile reads whole file appending its content to buf, files of procfs report no size,
+// so buf grows as they are read; the grown buffer is returned even if reading failed
+func ReadFile(path string, buf []byte) ([]byte, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return buf, err
+	}
+	defer fd.Close()
+	return ReadAll(fd, buf)
+}
+
+// ReadAll reads r until EOF appending its content to buf, the grown buffer is returned
+// even if reading failed
+func ReadAll(r io.Reader, buf []byte) ([]byte, error) {
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+}
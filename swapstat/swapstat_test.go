@@ -0,0 +1,116 @@
+// +build linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015-2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package swapstat
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshot(t *testing.T) {
+	procPath, _ := ioutil.TempDir("", "swapstat")
+	defer os.RemoveAll(procPath)
+	write := func(file string, content string) {
+		ioutil.WriteFile(filepath.Join(procPath, file), []byte(content), 0644)
+	}
+	write(SwapsFile, "Filename Type Size Used Priority\n/dev/sda5 partition 99999 6666 -1\n")
+	write(MemInfoFile, "MemTotal: 400000 kB\nSwapTotal: 99999 kB\nSwapFree: 93333 kB\n")
+	write(StatFile, "page 33333 44444\n")
+	Convey("snapshot is read", t, func() {
+		write(VMStatFile, "pswpin 11111\npswpout 22222\n")
+		s, err := ReadSnapshot(procPath)
+		So(err, ShouldBeNil)
+		So(len(s.Devices), ShouldEqual, 1)
+		So(s.Devices[0].Used, ShouldEqual, 6666*1024)
+		So(s.MemInfo.SwapFree, ShouldEqual, 93333*1024)
+		So(s.VMStat, ShouldResemble, VMStat{PswpIn: 11111, PswpOut: 22222})
+		So(s.Time.IsZero(), ShouldBeFalse)
+	})
+	Convey("swap IO is read from stat of old kernels", t, func() {
+		os.Remove(filepath.Join(procPath, VMStatFile))
+		s, err := ReadSnapshot(procPath)
+		So(err, ShouldBeNil)
+		So(s.VMStat, ShouldResemble, VMStat{PswpIn: 33333, PswpOut: 44444})
+	})
+	Convey("errors point to file", t, func() {
+		write(VMStatFile, "pswpin 11111\npswpout 22222\n")
+		write(MemInfoFile, "MemTotal: 400000 kB\nSwapTotal: x kB\n")
+		_, err := ReadSnapshot(procPath)
+		So(err, ShouldNotBeNil)
+		So(err.(*ParseError).File, ShouldEqual, filepath.Join(procPath, MemInfoFile))
+		So(err.Error(), ShouldEqual, "SwapTotal is not a number: x ("+filepath.Join(procPath, MemInfoFile)+":2)")
+		os.Remove(filepath.Join(procPath, SwapsFile))
+		_, err = ReadSnapshot(procPath)
+		So(err, ShouldNotBeNil)
+		_, ok := err.(*SourceError)
+		So(ok, ShouldBeTrue)
+		So(os.IsNotExist(Cause(err)), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "no such file or directory")
+	})
+	Convey("errors wrap underlying errors", t, func() {
+		write(SwapsFile, "Filename Type Size Used Priority\n/dev/sda5 partition 99999 6666 -1\n")
+		write(MemInfoFile, "MemTotal: 400000 kB\nSwapTotal: x kB\n")
+		_, err := ReadSnapshot(procPath)
+		var numErr *strconv.NumError
+		So(errors.As(err, &numErr), ShouldBeTrue)
+		So(numErr.Num, ShouldEqual, "x")
+		write(MemInfoFile, "MemTotal: 400000 kB\nSwapTotal:\n")
+		_, err = ReadSnapshot(procPath)
+		So(errors.Is(err, ErrMissingField), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "SwapTotal is missing ("+filepath.Join(procPath, MemInfoFile)+":2)")
+		write(MemInfoFile, "MemTotal: 400000 kB\nSwapTotal: 99999 kB\nSwapFree: 93333 kB\n")
+		os.Remove(filepath.Join(procPath, SwapsFile))
+		_, err = ReadSnapshot(procPath)
+		So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+		var pathErr *os.PathError
+		So(errors.As(err, &pathErr), ShouldBeTrue)
+		So(pathErr.Path, ShouldEqual, filepath.Join(procPath, SwapsFile))
+	})
+	Convey("delta and rate of swap IO", t, func() {
+		now := time.Now()
+		prev := &Snapshot{Time: now, VMStat: VMStat{PswpIn: 100, PswpOut: 200}}
+		cur := &Snapshot{Time: now.Add(2 * time.Second), VMStat: VMStat{PswpIn: 110, PswpOut: 240}}
+		delta, err := cur.Delta(prev)
+		So(err, ShouldBeNil)
+		So(delta, ShouldResemble, Delta{PagesIn: 10, PagesOut: 40, Duration: 2 * time.Second})
+		So(delta.Rate(4096), ShouldResemble, Rate{
+			InPagesPerSec:  5,
+			OutPagesPerSec: 20,
+			InBytesPerSec:  5 * 4096,
+			OutBytesPerSec: 20 * 4096,
+		})
+		_, err = prev.Delta(cur)
+		So(err, ShouldNotBeNil)
+		cur.Time = now.Add(time.Second)
+		cur.VMStat.PswpIn = 50
+		_, err = cur.Delta(prev)
+		So(err, ShouldEqual, ErrCounterReset)
+		So(Delta{}.Rate(4096), ShouldResemble, Rate{})
+	})
+}